@@ -0,0 +1,116 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path"
+	"time"
+)
+
+// CommandCache memoizes the output of a list or detail command.
+type CommandCache struct {
+	Ttl string `json:"ttl" yaml:"ttl"`
+}
+
+func (c CommandCache) Duration() (time.Duration, error) {
+	return time.ParseDuration(c.Ttl)
+}
+
+// OutputCache stores the output of the commands of an extension.
+// The keys are hashed, so they can contain the rendered exec string along with its environment.
+type OutputCache struct {
+	dir string
+}
+
+// OutputCache returns the cache of the command outputs, it lives in the cache directory of the extension.
+// The directory is only created once an output is stored.
+func (e Extension) OutputCache() (OutputCache, error) {
+	cacheDir, err := e.CacheDir()
+	if err != nil {
+		return OutputCache{}, err
+	}
+
+	return OutputCache{dir: path.Join(cacheDir, ".output")}, nil
+}
+
+func (c OutputCache) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return path.Join(c.dir, hex.EncodeToString(hash[:]))
+}
+
+// Get returns the cached output and its age.
+func (c OutputCache) Get(key string) (output string, age time.Duration, ok bool) {
+	entryPath := c.path(key)
+	info, err := os.Stat(entryPath)
+	if err != nil {
+		return "", 0, false
+	}
+
+	content, err := os.ReadFile(entryPath)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return string(content), time.Since(info.ModTime()), true
+}
+
+func (c OutputCache) Set(key string, output string) error {
+	if _, err := ensureDir(c.dir); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so that concurrent reads never see a partial entry
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(output); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// DirSize returns the total size of the files of a directory, missing directories are empty.
+func DirSize(dir string) (int64, error) {
+	var size int64
+	err := walkFiles(dir, func(info os.FileInfo) {
+		size += info.Size()
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	return size, err
+}
+
+func walkFiles(dir string, fn func(os.FileInfo)) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := walkFiles(path.Join(dir, entry.Name()), fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		fn(info)
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/sunbeamlauncher/sunbeam/utils"
+)
+
+func TestOutputCache(t *testing.T) {
+	dir := t.TempDir()
+	extension := Extension{Name: "jira", Dirs: utils.Dirs{Cache: dir}}
+
+	cache, err := extension.OutputCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, ok := cache.Get("list-issues"); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+
+	// Reading the cache must not create its directories
+	if _, err := os.Stat(path.Join(dir, "jira")); !os.IsNotExist(err) {
+		t.Fatalf("expected the cache directory to be missing, got %v", err)
+	}
+
+	if err := cache.Set("list-issues", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set("list-issues", "second"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Set("list-projects", "projects"); err != nil {
+		t.Fatal(err)
+	}
+
+	output, age, ok := cache.Get("list-issues")
+	if !ok || output != "second" || age < 0 {
+		t.Errorf("expected the last output, got %q, %s, %v", output, age, ok)
+	}
+	if output, _, _ := cache.Get("list-projects"); output != "projects" {
+		t.Errorf("expected the outputs to be stored separately, got %q", output)
+	}
+
+	// No temporary file is left behind
+	entries, err := os.ReadDir(path.Join(dir, "jira", ".output"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(entries))
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(path.Join(dir, "nested", "deeper"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, size := range map[string]int{
+		"a.txt":                    10,
+		"nested/b.txt":             20,
+		"nested/deeper/c.txt":      30,
+		"nested/deeper/empty.json": 0,
+	} {
+		if err := os.WriteFile(path.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	size, err := DirSize(dir)
+	if err != nil || size != 60 {
+		t.Errorf("expected 60 bytes, got %d, %v", size, err)
+	}
+
+	size, err = DirSize(path.Join(dir, "missing"))
+	if err != nil || size != 0 {
+		t.Errorf("expected a missing directory to be empty, got %d, %v", size, err)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
-	"os/exec"
 	"strings"
 	"text/template"
 
@@ -27,10 +26,39 @@ type Command struct {
 	Preferences []ScriptInput `json:"preferences" yaml:"preferences"`
 	Inputs      []ScriptInput `json:"inputs" yaml:"inputs"`
 	Page        Page          `json:"page" yaml:"page"`
+	Confirm     Confirm       `json:"confirm" yaml:"confirm"`
+	Interactive bool          `json:"interactive" yaml:"interactive"`
+	Cache       *CommandCache `json:"cache" yaml:"cache"`
 
 	OnSuccess string `json:"onSuccess" yaml:"onSuccess"`
 }
 
+// Confirm asks the user for a confirmation before running a command.
+// It is either a boolean or a custom message.
+type Confirm struct {
+	Required bool
+	Message  string
+}
+
+func (c *Confirm) UnmarshalJSON(data []byte) error {
+	var message string
+	if err := json.Unmarshal(data, &message); err == nil {
+		c.Required, c.Message = true, message
+		return nil
+	}
+
+	return json.Unmarshal(data, &c.Required)
+}
+
+func (c *Confirm) UnmarshalYAML(value *yaml.Node) error {
+	if value.Tag == "!!bool" {
+		return value.Decode(&c.Required)
+	}
+
+	c.Required = true
+	return value.Decode(&c.Message)
+}
+
 type Optional[T any] struct {
 	Defined bool
 	Value   T
@@ -143,12 +171,21 @@ type Detail struct {
 
 type DetailData struct {
 	Preview   string           `json:"preview"`
+	Format    string           `json:"format"`
+	Image     string           `json:"image"`
 	Metadatas []ScriptMetadata `json:"metadatas"`
 }
 
 type ScriptMetadata struct {
-	Title string `json:"title"`
-	Value string `json:"value"`
+	Title string        `json:"title"`
+	Type  string        `json:"type"`
+	Value string        `json:"value"`
+	Tags  []MetadataTag `json:"tags"`
+}
+
+type MetadataTag struct {
+	Text  string `json:"text"`
+	Color string `json:"color"`
 }
 
 type ScriptItem struct {
@@ -156,12 +193,51 @@ type ScriptItem struct {
 	Title    string `json:"title"`
 	Subtitle string `json:"subtitle"`
 	DetailData
-	Accessories []string       `json:"accessories"`
-	Actions     []ScriptAction `json:"actions"`
+	PreviewCommand *PreviewCommand `json:"previewCommand"`
+	Accessories    []string        `json:"accessories"`
+	Actions        []ScriptAction  `json:"actions"`
+}
+
+// PreviewCommand is run lazily to compute the preview of a list item.
+// It is either a raw exec string, or a reference to one of the extension commands.
+type PreviewCommand struct {
+	Exec   string                          `json:"exec"`
+	Script string                          `json:"script"`
+	With   map[string]ScriptInputWithValue `json:"with"`
+}
+
+func (pc *PreviewCommand) UnmarshalJSON(bytes []byte) error {
+	var exec string
+	if err := json.Unmarshal(bytes, &exec); err == nil {
+		pc.Exec = exec
+		return nil
+	}
+
+	type previewCommand PreviewCommand
+	return json.Unmarshal(bytes, (*previewCommand)(pc))
 }
 
-func (li ScriptItem) PreviewCommand() *exec.Cmd {
-	return nil
+// Cmd renders the command line of the preview command.
+func (pc PreviewCommand) Cmd(commands map[string]Command) (string, error) {
+	if pc.Script == "" {
+		return pc.Exec, nil
+	}
+
+	command, ok := commands[pc.Script]
+	if !ok {
+		return "", fmt.Errorf("script %s not found", pc.Script)
+	}
+
+	with := make(map[string]any)
+	for key, input := range pc.With {
+		value, err := input.GetValue()
+		if err != nil {
+			return "", err
+		}
+		with[key] = value
+	}
+
+	return command.Cmd(with)
 }
 
 type ScriptAction struct {
@@ -174,23 +250,54 @@ type ScriptAction struct {
 	Url  string `json:"url"`
 	Path string `json:"path"`
 
+	Target      string `json:"target"`
+	Application string `json:"application"`
+
 	Extension string `json:"extension"`
 	Script    string `json:"script"`
 	Command   string `json:"command"`
 	Dir       string
 
-	OnSuccess string                          `json:"onSuccess"`
-	With      map[string]ScriptInputWithValue `json:"with"`
+	OnSuccess     string                          `json:"onSuccess"`
+	CloseOnAction *bool                           `json:"closeOnAction"`
+	Confirm       Confirm                         `json:"confirm"`
+	With          map[string]ScriptInputWithValue `json:"with"`
 }
 
 //go:embed schemas/listitem.json
 var itemSchemaString string
 var itemSchema *jsonschema.Schema
 
+//go:embed schemas/detail.json
+var detailSchemaString string
+var detailSchema *jsonschema.Schema
+
 func init() {
+	itemSchemaUrl := "http://github.com/sunbeamlauncher/sunbeam/listitem.json"
+	detailSchemaUrl := "http://github.com/sunbeamlauncher/sunbeam/detail.json"
+
 	compiler := jsonschema.NewCompiler()
-	compiler.AddResource("listitem.json", strings.NewReader(itemSchemaString))
-	itemSchema = compiler.MustCompile("listitem.json")
+	compiler.AssertFormat = true
+	compiler.AddResource(itemSchemaUrl, strings.NewReader(itemSchemaString))
+	compiler.AddResource(detailSchemaUrl, strings.NewReader(detailSchemaString))
+	itemSchema = compiler.MustCompile(itemSchemaUrl)
+	detailSchema = compiler.MustCompile(detailSchemaUrl)
+}
+
+func ParseDetail(output string) (detail Detail, err error) {
+	var data any
+	err = json.Unmarshal([]byte(output), &data)
+	if err != nil {
+		return
+	}
+
+	err = detailSchema.Validate(data)
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal([]byte(output), &detail)
+	return
 }
 
 func ParseListItems(output string) (items []ScriptItem, err error) {
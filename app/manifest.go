@@ -2,14 +2,18 @@ package app
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/sunbeamlauncher/sunbeam/utils"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,17 +23,29 @@ var manifestSchema string
 type Api struct {
 	Extensions    []Extension
 	ExtensionRoot string
+	Dirs          utils.Dirs
+	// Cache skips the parsing of the unchanged manifests, it is optional
+	Cache *ManifestCache
 }
 
 func (api *Api) IsExtensionInstalled(name string) bool {
-	extensionDir := path.Join(api.ExtensionRoot, name)
-
-	if _, err := os.Stat(extensionDir); os.IsNotExist(err) {
+	if _, err := os.Stat(api.ExtensionPath(name)); os.IsNotExist(err) {
 		return false
 	}
 	return true
 }
 
+// ExtensionPath returns the directory of the extension, or the script of single-file extensions.
+func (api *Api) ExtensionPath(name string) string {
+	for _, extension := range api.Extensions {
+		if extension.Name == name && extension.ScriptFile != "" {
+			return extension.ScriptFile
+		}
+	}
+
+	return path.Join(api.ExtensionRoot, name)
+}
+
 type RootItem struct {
 	Extension string
 	Script    string
@@ -45,10 +61,64 @@ type Extension struct {
 	PostInstall string        `json:"postInstall" yaml:"postInstall"`
 	Root        string        `json:"root" yaml:"root"`
 	Preferences []ScriptInput `json:"preferences" yaml:"preferences"`
+	// PreferencesJson sends all the preferences as a single json document, either in an env variable or on a file descriptor
+	PreferencesJson string `json:"preferencesJson" yaml:"preferencesJson"`
 
 	Requirements []ExtensionRequirement `json:"requirements" yaml:"requirements"`
 	RootItems    []RootItem             `json:"rootItems" yaml:"rootItems"`
 	Commands     map[string]Command     `json:"commands" yaml:"commands"`
+	// ScriptFile is the path of single-file extensions, which embed their manifest
+	ScriptFile string `json:"-" yaml:"-"`
+
+	// Dirs are the directories of sunbeam, the directories of the extension are nested in them
+	Dirs utils.Dirs `json:"-" yaml:"-"`
+}
+
+// DataDir returns the directory where the extension persists its data.
+// It sits next to the extensions directory, so extensions cannot be named after it.
+func (e Extension) DataDir() (string, error) {
+	return e.dir(e.Dirs.Data, e.Name)
+}
+
+// CacheDir returns the directory where the extension caches its data.
+func (e Extension) CacheDir() (string, error) {
+	return e.dir(e.Dirs.Cache, e.Name)
+}
+
+// StateDir returns the directory where the extension keeps its state, such as history or logs.
+func (e Extension) StateDir() (string, error) {
+	return e.dir(e.Dirs.State, e.Name)
+}
+
+// EnsureDirs creates the data, cache and state directories of the extension.
+func (e Extension) EnsureDirs() error {
+	for _, dirFunc := range []func() (string, error){e.DataDir, e.CacheDir, e.StateDir} {
+		dir, err := dirFunc()
+		if err != nil {
+			return err
+		}
+
+		if _, err := ensureDir(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e Extension) dir(base string, elem ...string) (string, error) {
+	if base == "" {
+		return "", fmt.Errorf("the directories of extension %s are not resolved", e.Name)
+	}
+
+	return path.Join(append([]string{base}, elem...)...), nil
+}
+
+func ensureDir(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
 }
 
 type ExtensionRequirement struct {
@@ -78,37 +148,144 @@ func init() {
 	}
 }
 
-func (api *Api) LoadExtensions(extensionRoot string) error {
+// extensionSource is a manifest to load, either the sunbeam.yml of a directory or a single-file script.
+type extensionSource struct {
+	name   string
+	path   string
+	script bool
+	fi     os.FileInfo
+}
+
+// LoadExtensions loads the extensions of the root directory, or only the given ones if names are provided.
+// The manifests missing from the cache are parsed in parallel.
+func (api *Api) LoadExtensions(extensionRoot string, names ...string) error {
 	api.ExtensionRoot = extensionRoot
 	entries, err := os.ReadDir(extensionRoot)
 	if err != nil {
 		return fmt.Errorf("failed to read extension root: %w", err)
 	}
 
+	sources := make([]extensionSource, 0, len(entries))
+	scripts := make([]extensionSource, 0)
+	seen := make(map[string]struct{})
 	for _, entry := range entries {
 		extensionDir := path.Join(extensionRoot, entry.Name())
-		if fi, err := os.Stat(extensionDir); err != nil || !fi.IsDir() {
+		fi, err := os.Stat(extensionDir)
+		if err != nil {
 			continue
 		}
 
-		extensionName := entry.Name()
-		manifestPath := path.Join(extensionDir, "sunbeam.yml")
-		if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+		if !fi.IsDir() {
+			if !strings.HasPrefix(entry.Name(), ".") && IsScriptExtension(fi) {
+				scripts = append(scripts, extensionSource{
+					name:   strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())),
+					path:   extensionDir,
+					script: true,
+					fi:     fi,
+				})
+			}
 			continue
 		}
 
-		extension, err := ParseManifest(extensionName, manifestPath)
+		manifestPath := path.Join(extensionDir, "sunbeam.yml")
+		manifestInfo, err := os.Stat(manifestPath)
 		if err != nil {
-			log.Println(fmt.Errorf("error parsing manifest %s: %w", manifestPath, err))
+			continue
+		}
+
+		sources = append(sources, extensionSource{name: entry.Name(), path: manifestPath, fi: manifestInfo})
+		seen[entry.Name()] = struct{}{}
+	}
+
+	for _, source := range scripts {
+		if _, ok := seen[source.name]; ok {
+			log.Printf("skipping script %s, extension %s is already installed", source.path, source.name)
+			continue
 		}
 
-		extension.Root = extensionDir
+		sources = append(sources, source)
+		seen[source.name] = struct{}{}
+	}
+
+	if len(names) > 0 {
+		sources = filterSources(sources, names)
+	}
+
+	extensions := make([]Extension, len(sources))
+	errs := make([]error, len(sources))
+	cached := make([]bool, len(sources))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, runtime.NumCPU())
+	for i, source := range sources {
+		if api.Cache != nil {
+			if extensions[i], cached[i] = api.Cache.Get(source.path, source.fi); cached[i] {
+				continue
+			}
+		}
+
+		wg.Add(1)
+		go func(i int, source extensionSource) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if source.script {
+				extensions[i], errs[i] = ParseScriptExtension(source.name, source.path)
+			} else {
+				extensions[i], errs[i] = ParseManifest(source.name, source.path)
+			}
+		}(i, source)
+	}
+	wg.Wait()
+
+	for i, source := range sources {
+		extension := extensions[i]
+		if err := errs[i]; err != nil {
+			if source.script {
+				// Executables without annotations are not meant to be extensions
+				if !errors.Is(err, ErrNoScriptManifest) {
+					log.Println(fmt.Errorf("error parsing script %s: %w", source.path, err))
+				}
+				continue
+			}
+			log.Println(fmt.Errorf("error parsing manifest %s: %w", source.path, err))
+		}
+
+		if !source.script {
+			extension.Root = path.Dir(source.path)
+		}
+		extension.Dirs = api.Dirs
+
+		if api.Cache != nil && !cached[i] && errs[i] == nil {
+			api.Cache.Set(source.path, source.fi, extension)
+		}
 		api.Extensions = append(api.Extensions, extension)
 	}
 
+	if api.Cache != nil {
+		if err := api.Cache.Save(); err != nil {
+			log.Println(fmt.Errorf("failed to save the manifest cache: %w", err))
+		}
+	}
+
 	return nil
 }
 
+func filterSources(sources []extensionSource, names []string) []extensionSource {
+	filtered := make([]extensionSource, 0, len(names))
+	for _, source := range sources {
+		for _, name := range names {
+			if source.name == name {
+				filtered = append(filtered, source)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
 func ParseManifest(extensionName string, manifestPath string) (extension Extension, err error) {
 	manifestBytes, err := os.ReadFile(manifestPath)
 	if err != nil {
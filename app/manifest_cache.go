@@ -0,0 +1,133 @@
+package app
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path"
+	"time"
+)
+
+// manifestCacheFormat is bumped when the cached types change, to discard the entries encoded by older versions.
+const manifestCacheFormat = 1
+
+func init() {
+	// Values decoded from yaml manifests, e.g. the defaults of inputs
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+}
+
+// ManifestCache stores the parsed extensions, keyed by the path of their manifest.
+// An entry is invalidated when the modification time or the size of its manifest changes.
+// It is not safe for concurrent use.
+type ManifestCache struct {
+	path    string
+	version string
+	entries map[string]manifestCacheEntry
+	dirty   bool
+}
+
+type manifestCacheEntry struct {
+	ModTime   time.Time
+	Size      int64
+	Extension Extension
+}
+
+type manifestCacheFile struct {
+	Format  int
+	Version string
+	Entries map[string]manifestCacheEntry
+}
+
+// LoadManifestCache reads the cache file, the cache starts empty if the file is missing, corrupt, or written by another version of sunbeam.
+func LoadManifestCache(cachePath string, version string) *ManifestCache {
+	cache := &ManifestCache{
+		path:    cachePath,
+		version: version,
+		entries: make(map[string]manifestCacheEntry),
+	}
+
+	content, err := os.ReadFile(cachePath)
+	if err != nil {
+		return cache
+	}
+
+	var file manifestCacheFile
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&file); err != nil {
+		return cache
+	}
+
+	if file.Format != manifestCacheFormat || file.Version != version || file.Entries == nil {
+		return cache
+	}
+
+	cache.entries = file.Entries
+	return cache
+}
+
+// Get returns the cached extension of the manifest, if the manifest did not change since it was parsed.
+func (c *ManifestCache) Get(manifestPath string, fi os.FileInfo) (Extension, bool) {
+	entry, ok := c.entries[manifestPath]
+	if !ok || !entry.ModTime.Equal(fi.ModTime()) || entry.Size != fi.Size() {
+		return Extension{}, false
+	}
+
+	return entry.Extension, true
+}
+
+func (c *ManifestCache) Set(manifestPath string, fi os.FileInfo, extension Extension) {
+	c.entries[manifestPath] = manifestCacheEntry{
+		ModTime:   fi.ModTime(),
+		Size:      fi.Size(),
+		Extension: extension,
+	}
+	c.dirty = true
+}
+
+// Save writes the cache file if it was modified, the entries of the removed manifests are dropped.
+func (c *ManifestCache) Save() error {
+	if !c.dirty {
+		return nil
+	}
+
+	for manifestPath := range c.entries {
+		if _, err := os.Stat(manifestPath); err != nil {
+			delete(c.entries, manifestPath)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(manifestCacheFile{
+		Format:  manifestCacheFormat,
+		Version: c.version,
+		Entries: c.entries,
+	}); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(path.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	// The file is replaced atomically, since several instances of sunbeam can run at the same time
+	tmp, err := os.CreateTemp(path.Dir(c.path), ".manifests-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunbeamlauncher/sunbeam/utils"
+)
+
+const testManifest = `title: %s
+version: "1.0"
+preferences:
+  - name: token
+    type: password
+    title: Token
+rootItems:
+  - title: List Issues
+    script: list-issues
+    with:
+      query: is:open
+commands:
+  list-issues:
+    exec: ./issues.sh ${{ query }}
+    onSuccess: push-page
+    confirm: Are you sure?
+    page:
+      type: list
+    inputs:
+      - name: query
+        type: textfield
+        title: Query
+        defaultValue: is:open
+      - name: closed
+        type: checkbox
+        title: Closed
+        label: Include closed issues
+        defaultValue: false
+`
+
+func writeExtensions(t testing.TB, n int) string {
+	root := t.TempDir()
+	for i := 0; i < n; i++ {
+		dir := path.Join(root, fmt.Sprintf("extension-%d", i))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path.Join(dir, "sunbeam.yml"), []byte(fmt.Sprintf(testManifest, "Issues")), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func TestManifestCache(t *testing.T) {
+	root := writeExtensions(t, 3)
+	cachePath := path.Join(t.TempDir(), "manifests.gob")
+
+	var uncached Api
+	if err := uncached.LoadExtensions(root); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		api := Api{Cache: LoadManifestCache(cachePath, "test")}
+		if err := api.LoadExtensions(root); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(api.Extensions, uncached.Extensions) {
+			t.Fatalf("cached extensions differ:\n%+v\n%+v", api.Extensions, uncached.Extensions)
+		}
+	}
+
+	// A manifest with the same size and modification time is not parsed again
+	manifestPath := path.Join(root, "extension-0", "sunbeam.yml")
+	fi, err := os.Stat(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(manifestPath, []byte(fmt.Sprintf(testManifest, "Tissue")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(manifestPath, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	api := Api{Cache: LoadManifestCache(cachePath, "test")}
+	if err := api.LoadExtensions(root, "extension-0"); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.Extensions) != 1 || api.Extensions[0].Title != "Issues" {
+		t.Fatalf("expected the cached extension, got %+v", api.Extensions)
+	}
+
+	// The entry is invalidated once the modification time changes
+	if err := os.Chtimes(manifestPath, time.Now(), fi.ModTime().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	api = Api{Cache: LoadManifestCache(cachePath, "test")}
+	if err := api.LoadExtensions(root, "extension-0"); err != nil {
+		t.Fatal(err)
+	}
+	if api.Extensions[0].Title != "Tissue" {
+		t.Errorf("expected the updated manifest, got %s", api.Extensions[0].Title)
+	}
+
+	// The cache is discarded when sunbeam is upgraded
+	if cache := LoadManifestCache(cachePath, "other"); len(cache.entries) != 0 {
+		t.Errorf("expected an empty cache, got %d entries", len(cache.entries))
+	}
+}
+
+// BenchmarkLoadExtensions measures the time spent loading 50 extensions, with and without the manifest cache.
+func BenchmarkLoadExtensions(b *testing.B) {
+	root := writeExtensions(b, 50)
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var api Api
+			if err := api.LoadExtensions(root); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		cachePath := path.Join(b.TempDir(), "manifests.gob")
+		warm := Api{Cache: LoadManifestCache(cachePath, "bench")}
+		if err := warm.LoadExtensions(root); err != nil {
+			b.Fatal(err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			api := Api{Cache: LoadManifestCache(cachePath, "bench")}
+			if err := api.LoadExtensions(root); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("single", func(b *testing.B) {
+		cachePath := path.Join(b.TempDir(), "manifests.gob")
+		for i := 0; i < b.N; i++ {
+			api := Api{Cache: LoadManifestCache(cachePath, "bench")}
+			if err := api.LoadExtensions(root, "extension-25"); err != nil {
+				b.Fatal(err)
+			}
+			if len(api.Extensions) != 1 || !strings.HasSuffix(api.Extensions[0].Root, "extension-25") {
+				b.Fatalf("unexpected extensions: %v", api.Extensions)
+			}
+		}
+	})
+}
+
+func TestExtensionDirs(t *testing.T) {
+	extension := Extension{Name: "jira", Dirs: utils.Dirs{Data: "/sunbeam/data", Cache: "/sunbeam/cache", State: "/sunbeam/state"}}
+
+	cases := []struct {
+		dirFunc func() (string, error)
+		want    string
+	}{
+		{extension.DataDir, "/sunbeam/data/jira"},
+		{extension.CacheDir, "/sunbeam/cache/jira"},
+		{extension.StateDir, "/sunbeam/state/jira"},
+	}
+	for _, tc := range cases {
+		if dir, err := tc.dirFunc(); err != nil || dir != tc.want {
+			t.Errorf("expected %s, got %s, %v", tc.want, dir, err)
+		}
+	}
+
+	if _, err := (Extension{Name: "jira"}).DataDir(); err == nil {
+		t.Error("expected an error when the directories are not resolved")
+	}
+}
@@ -0,0 +1,497 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sunbeamlauncher/sunbeam/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	PreferenceScopeEnv       = "env"
+	PreferenceScopeDirectory = "directory"
+	PreferenceScopeProfile   = "profile"
+	PreferenceScopeGlobal    = "global"
+)
+
+type Preference struct {
+	Name      string `json:"name"`
+	Script    string `json:"script"`
+	Extension string `json:"extension"`
+	Value     any    `json:"value"`
+
+	// Scope is the origin of the preference, it is only set when the preference is read
+	Scope string `json:"-"`
+}
+
+// Preferences resolves the preferences of the extensions and of their commands, by order of precedence:
+// environment variables, the .sunbeam.yml file of the working directory, the active profile, and the global preferences.
+// In each scope, command preferences take precedence over the extension preferences with the same name.
+type Preferences struct {
+	global    *preferenceStore
+	profile   *preferenceStore
+	directory map[string]Preference
+
+	lookupEnv func(string) (string, bool)
+}
+
+// LoadPreferences reads the global preferences from a json file, the file is created once a preference is set.
+func LoadPreferences(preferencePath string) (*Preferences, error) {
+	global, err := loadPreferenceStore(preferencePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Preferences{
+		global:    global,
+		directory: make(map[string]Preference),
+		lookupEnv: os.LookupEnv,
+	}, nil
+}
+
+// UseProfile loads the preferences of a profile, they override the global ones and receive the new values.
+func (p *Preferences) UseProfile(preferencePath string) error {
+	profile, err := loadPreferenceStore(preferencePath)
+	if err != nil {
+		return err
+	}
+
+	p.profile = profile
+	return nil
+}
+
+// UseDirectory overrides the stored preferences with the ones of a directory config.
+func (p *Preferences) UseDirectory(config DirectoryConfig) {
+	p.directory = make(map[string]Preference)
+	for key, values := range config.Preferences {
+		extension, script, _ := strings.Cut(key, ".")
+		for name, value := range values {
+			p.directory[preferenceId(extension, script, name)] = Preference{
+				Name:      name,
+				Script:    script,
+				Extension: extension,
+				Value:     value,
+			}
+		}
+	}
+}
+
+func preferenceId(extension string, script string, name string) string {
+	if script != "" {
+		return fmt.Sprintf("%s.%s.%s", extension, script, name)
+	}
+	return fmt.Sprintf("%s.%s", extension, name)
+}
+
+// lookup returns the preference of the command, or the preference of the extension if the command does not define it.
+func lookup(values map[string]Preference, extension string, script string, name string) (Preference, bool) {
+	if script != "" {
+		if preference, ok := values[preferenceId(extension, script, name)]; ok {
+			return preference, true
+		}
+	}
+
+	preference, ok := values[preferenceId(extension, "", name)]
+	return preference, ok
+}
+
+func (p *Preferences) Get(extension string, script string, name string) (Preference, bool) {
+	if value, ok := p.lookupEnv(name); ok {
+		return Preference{
+			Name:      name,
+			Script:    script,
+			Extension: extension,
+			Value:     value,
+			Scope:     PreferenceScopeEnv,
+		}, true
+	}
+
+	for _, scope := range p.scopes() {
+		if preference, ok := lookup(scope.values, extension, script, name); ok {
+			preference.Scope = scope.name
+			return preference, true
+		}
+	}
+
+	return Preference{}, false
+}
+
+// GetStored returns the preference from the profile or the global store, ignoring the env and directory overrides.
+// Forms are pre-filled with it, so that saving them does not persist the overrides.
+func (p *Preferences) GetStored(extension string, script string, name string) (Preference, bool) {
+	for _, scope := range p.scopes() {
+		if scope.name == PreferenceScopeDirectory {
+			continue
+		}
+
+		if preference, ok := lookup(scope.values, extension, script, name); ok {
+			preference.Scope = scope.name
+			return preference, true
+		}
+	}
+
+	return Preference{}, false
+}
+
+type preferenceScope struct {
+	name   string
+	values map[string]Preference
+}
+
+// scopes returns the stored scopes, by order of precedence.
+func (p *Preferences) scopes() []preferenceScope {
+	scopes := []preferenceScope{{PreferenceScopeDirectory, p.directory}}
+	if p.profile != nil {
+		scopes = append(scopes, preferenceScope{PreferenceScopeProfile, p.profile.values})
+	}
+	return append(scopes, preferenceScope{PreferenceScopeGlobal, p.global.values})
+}
+
+// writable returns the store receiving the new values, the profile one if a profile is active.
+func (p *Preferences) writable() *preferenceStore {
+	if p.profile != nil {
+		return p.profile
+	}
+	return p.global
+}
+
+func (p *Preferences) Set(preferences ...Preference) error {
+	store := p.writable()
+	for _, preference := range preferences {
+		preference.Scope = ""
+		store.values[preferenceId(preference.Extension, preference.Script, preference.Name)] = preference
+	}
+
+	return store.save()
+}
+
+// Unset removes a stored preference, it reports whether the preference was defined.
+func (p *Preferences) Unset(extension string, script string, name string) (bool, error) {
+	store := p.writable()
+	id := preferenceId(extension, script, name)
+	if _, ok := store.values[id]; !ok {
+		return false, nil
+	}
+
+	delete(store.values, id)
+	return true, store.save()
+}
+
+// List returns the stored preferences of an extension, or all of them if the extension is empty.
+// Overridden preferences are omitted.
+func (p *Preferences) List(extension string) []Preference {
+	preferences := make([]Preference, 0)
+	seen := make(map[string]struct{})
+	for _, scope := range p.scopes() {
+		for id, preference := range scope.values {
+			if extension != "" && preference.Extension != extension {
+				continue
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+
+			seen[id] = struct{}{}
+			preference.Scope = scope.name
+			preferences = append(preferences, preference)
+		}
+	}
+
+	sort.Slice(preferences, func(i, j int) bool {
+		return preferenceId(preferences[i].Extension, preferences[i].Script, preferences[i].Name) < preferenceId(preferences[j].Extension, preferences[j].Script, preferences[j].Name)
+	})
+
+	return preferences
+}
+
+type preferenceStore struct {
+	path   string
+	values map[string]Preference
+}
+
+func loadPreferenceStore(preferencePath string) (*preferenceStore, error) {
+	values := make(map[string]Preference)
+	if _, err := os.Stat(preferencePath); os.IsNotExist(err) {
+		return &preferenceStore{
+			path:   preferencePath,
+			values: values,
+		}, nil
+	}
+
+	content, err := os.ReadFile(preferencePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read preferences: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse preferences %s: %w", preferencePath, err)
+	}
+
+	return &preferenceStore{
+		path:   preferencePath,
+		values: values,
+	}, nil
+}
+
+func (s *preferenceStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(path.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create preferences directory: %w", err)
+	}
+
+	content, err := json.Marshal(s.values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal preferences: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, content, 0600); err != nil {
+		return fmt.Errorf("failed to write preferences: %w", err)
+	}
+
+	return nil
+}
+
+const DirectoryConfigName = ".sunbeam.yml"
+
+// DirectoryConfig overrides the settings of sunbeam for a directory tree.
+type DirectoryConfig struct {
+	// Profile is used when no profile is selected explicitly
+	Profile string `yaml:"profile"`
+	// Preferences are keyed by extension, or by extension and command joined with a dot, e.g. jira.list-issues
+	Preferences map[string]map[string]any `yaml:"preferences"`
+}
+
+// ErrUntrustedDirectoryConfig is returned for the directory configs which were not trusted by the user, or modified since.
+var ErrUntrustedDirectoryConfig = errors.New("untrusted directory config")
+
+// FindDirectoryConfig returns the path of the closest .sunbeam.yml file, from the directory to the root of the filesystem.
+// The path is empty if there is none.
+func FindDirectoryConfig(dir string) (string, error) {
+	for {
+		configPath := path.Join(dir, DirectoryConfigName)
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		if utils.IsRoot(dir) {
+			return "", nil
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// LoadDirectoryConfig parses a directory config, if it is trusted.
+// Directory configs can come with a cloned repository, so they are only applied once trusted.
+func LoadDirectoryConfig(configPath string, trusted *TrustedConfigs) (DirectoryConfig, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return DirectoryConfig{}, err
+	}
+
+	if !trusted.IsTrusted(configPath, content) {
+		return DirectoryConfig{}, fmt.Errorf("%s: %w", configPath, ErrUntrustedDirectoryConfig)
+	}
+
+	return parseDirectoryConfig(configPath, content)
+}
+
+func parseDirectoryConfig(configPath string, content []byte) (DirectoryConfig, error) {
+	var config DirectoryConfig
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return DirectoryConfig{}, fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+
+	return config, nil
+}
+
+// TrustedConfigs records the directory configs trusted by the user, with the checksum of their content.
+// A modified config must be trusted again.
+type TrustedConfigs struct {
+	path      string
+	checksums map[string]string
+}
+
+func LoadTrustedConfigs(trustPath string) (*TrustedConfigs, error) {
+	trusted := &TrustedConfigs{
+		path:      trustPath,
+		checksums: make(map[string]string),
+	}
+
+	content, err := os.ReadFile(trustPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return trusted, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &trusted.checksums); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", trustPath, err)
+	}
+
+	return trusted, nil
+}
+
+func (t *TrustedConfigs) IsTrusted(configPath string, content []byte) bool {
+	checksum, ok := t.checksums[configPath]
+	return ok && checksum == configChecksum(content)
+}
+
+// Trust parses the directory config before trusting it, so that invalid configs are reported.
+func (t *TrustedConfigs) Trust(configPath string) error {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := parseDirectoryConfig(configPath, content); err != nil {
+		return err
+	}
+
+	t.checksums[configPath] = configChecksum(content)
+	return t.save()
+}
+
+// Untrust reports whether the directory config was trusted.
+func (t *TrustedConfigs) Untrust(configPath string) (bool, error) {
+	if _, ok := t.checksums[configPath]; !ok {
+		return false, nil
+	}
+
+	delete(t.checksums, configPath)
+	return true, t.save()
+}
+
+func (t *TrustedConfigs) save() error {
+	if err := os.MkdirAll(path.Dir(t.path), 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(t.checksums, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(t.path, content, 0600)
+}
+
+func configChecksum(content []byte) string {
+	checksum := sha256.Sum256(content)
+	return hex.EncodeToString(checksum[:])
+}
+
+// ParseValue converts a raw value to the type of the input, e.g. from the command line.
+func (si ScriptInput) ParseValue(raw string) (any, error) {
+	switch si.Type {
+	case "checkbox":
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a boolean, got %q", si.Name, raw)
+		}
+		return value, nil
+	case "dropdown":
+		choices := make([]string, 0, len(si.Data))
+		for _, choice := range si.Data {
+			if choice.Value == raw {
+				return raw, nil
+			}
+			choices = append(choices, choice.Value)
+		}
+		return nil, fmt.Errorf("%s expects one of %v, got %q", si.Name, choices, raw)
+	case "file", "directory":
+		resolved, err := utils.ResolvePath(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := os.Stat(resolved)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s expects an existing %s, %s does not exist", si.Name, si.Type, resolved)
+		} else if err != nil {
+			return nil, err
+		}
+
+		if info.IsDir() != (si.Type == "directory") {
+			return nil, fmt.Errorf("%s expects a %s, %s is not one", si.Name, si.Type, resolved)
+		}
+		return resolved, nil
+	default:
+		return raw, nil
+	}
+}
+
+// TypedValue converts a preference value to the type of the input.
+// Checkboxes are booleans, and the other inputs strings. Lists and maps are kept as is.
+func (si ScriptInput) TypedValue(value any) (any, error) {
+	if si.Type == "checkbox" {
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s expects a boolean, got %q", si.Name, v)
+			}
+			return b, nil
+		default:
+			return nil, fmt.Errorf("%s expects a boolean, got %v", si.Name, v)
+		}
+	}
+
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case bool:
+		s = strconv.FormatBool(v)
+	case int:
+		s = strconv.Itoa(v)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case []any, map[string]any:
+		return v, nil
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	if s != "" && (si.Type == "file" || si.Type == "directory") {
+		return utils.ResolvePath(s)
+	}
+
+	return s, nil
+}
+
+// EncodeValue serializes a preference value for an environment variable.
+// Checkboxes are encoded as true or false, lists and maps as json.
+func (si ScriptInput) EncodeValue(value any) (string, error) {
+	typed, err := si.TypedValue(value)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := typed.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %s: %w", si.Name, err)
+		}
+		return string(encoded), nil
+	}
+}
@@ -0,0 +1,244 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestPreferencesPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	preferences, err := LoadPreferences(path.Join(dir, "preferences.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{}
+	preferences.lookupEnv = func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	if err := preferences.Set(
+		Preference{Extension: "jira", Name: "HOST", Value: "global"},
+		Preference{Extension: "jira", Name: "TOKEN", Value: "global"},
+		Preference{Extension: "jira", Script: "list-issues", Name: "TOKEN", Value: "global-command"},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(script string, name string, value any, scope string) {
+		t.Helper()
+		preference, ok := preferences.Get("jira", script, name)
+		if !ok || preference.Value != value || preference.Scope != scope {
+			t.Errorf("expected %v from %s, got %+v", value, scope, preference)
+		}
+	}
+
+	expect("", "HOST", "global", PreferenceScopeGlobal)
+	// Command preferences take precedence over the extension ones
+	expect("list-issues", "TOKEN", "global-command", PreferenceScopeGlobal)
+	expect("list-issues", "HOST", "global", PreferenceScopeGlobal)
+
+	if err := preferences.UseProfile(path.Join(dir, "profiles", "work", "preferences.json")); err != nil {
+		t.Fatal(err)
+	}
+	if err := preferences.Set(Preference{Extension: "jira", Name: "HOST", Value: "profile"}); err != nil {
+		t.Fatal(err)
+	}
+	expect("", "HOST", "profile", PreferenceScopeProfile)
+	expect("", "TOKEN", "global", PreferenceScopeGlobal)
+
+	preferences.UseDirectory(DirectoryConfig{
+		Preferences: map[string]map[string]any{
+			"jira": {"HOST": "directory"},
+		},
+	})
+	expect("", "HOST", "directory", PreferenceScopeDirectory)
+
+	env["HOST"] = "env"
+	expect("", "HOST", "env", PreferenceScopeEnv)
+
+	// The stored value ignores the env and directory overrides
+	if preference, ok := preferences.GetStored("jira", "", "HOST"); !ok || preference.Value != "profile" || preference.Scope != PreferenceScopeProfile {
+		t.Errorf("expected the profile value, got %+v", preference)
+	}
+
+	listed := preferences.List("jira")
+	if len(listed) != 3 || listed[0].Value != "directory" {
+		t.Errorf("unexpected preferences: %+v", listed)
+	}
+
+	// The values set while a profile is active are stored in the profile
+	global, err := LoadPreferences(path.Join(dir, "preferences.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preference, _ := global.Get("jira", "", "HOST"); preference.Value != "global" {
+		t.Errorf("expected the global value to be kept, got %v", preference.Value)
+	}
+}
+
+func TestTrustedConfigs(t *testing.T) {
+	dir := t.TempDir()
+	configPath := path.Join(dir, DirectoryConfigName)
+	if err := os.WriteFile(configPath, []byte("profile: work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	subdir := path.Join(dir, "nested", "project")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	found, err := FindDirectoryConfig(subdir)
+	if err != nil || found != configPath {
+		t.Fatalf("expected %s, got %s, %v", configPath, found, err)
+	}
+
+	trustPath := path.Join(dir, "state", "trusted.json")
+	trusted, err := LoadTrustedConfigs(trustPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadDirectoryConfig(configPath, trusted); !errors.Is(err, ErrUntrustedDirectoryConfig) {
+		t.Fatalf("expected the config to be untrusted, got %v", err)
+	}
+
+	if err := trusted.Trust(configPath); err != nil {
+		t.Fatal(err)
+	}
+
+	trusted, err = LoadTrustedConfigs(trustPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadDirectoryConfig(configPath, trusted)
+	if err != nil || config.Profile != "work" {
+		t.Fatalf("expected the trusted config, got %+v, %v", config, err)
+	}
+
+	// A modified config must be trusted again
+	if err := os.WriteFile(configPath, []byte("profile: personal\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDirectoryConfig(configPath, trusted); !errors.Is(err, ErrUntrustedDirectoryConfig) {
+		t.Errorf("expected the modified config to be untrusted, got %v", err)
+	}
+
+	// Invalid configs cannot be trusted
+	if err := os.WriteFile(configPath, []byte("preferences: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := trusted.Trust(configPath); err == nil {
+		t.Error("expected an error for an invalid config")
+	}
+
+	if ok, err := trusted.Untrust(configPath); !ok || err != nil {
+		t.Errorf("expected the config to be untrusted, got %v, %v", ok, err)
+	}
+	if ok, _ := trusted.Untrust(configPath); ok {
+		t.Error("expected the config to be already untrusted")
+	}
+}
+
+func testInput(t *testing.T, document string) ScriptInput {
+	var input ScriptInput
+	if err := json.Unmarshal([]byte(document), &input); err != nil {
+		t.Fatal(err)
+	}
+	return input
+}
+
+func TestParseValue(t *testing.T) {
+	dir := t.TempDir()
+	file := path.Join(dir, "file.txt")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		input   string
+		raw     string
+		want    any
+		wantErr bool
+	}{
+		{input: `{"name": "host", "type": "textfield"}`, raw: "example.com", want: "example.com"},
+		{input: `{"name": "token", "type": "password"}`, raw: "", want: ""},
+		{input: `{"name": "closed", "type": "checkbox"}`, raw: "true", want: true},
+		{input: `{"name": "closed", "type": "checkbox"}`, raw: "0", want: false},
+		{input: `{"name": "closed", "type": "checkbox"}`, raw: "yes", wantErr: true},
+		{input: `{"name": "env", "type": "dropdown", "data": [{"title": "Prod", "value": "prod"}]}`, raw: "prod", want: "prod"},
+		{input: `{"name": "env", "type": "dropdown", "data": [{"title": "Prod", "value": "prod"}]}`, raw: "Prod", wantErr: true},
+		{input: `{"name": "config", "type": "file"}`, raw: file, want: file},
+		{input: `{"name": "config", "type": "file"}`, raw: dir, wantErr: true},
+		{input: `{"name": "root", "type": "directory"}`, raw: dir, want: dir},
+		{input: `{"name": "root", "type": "directory"}`, raw: file, wantErr: true},
+		{input: `{"name": "root", "type": "directory"}`, raw: path.Join(dir, "missing"), wantErr: true},
+	}
+
+	for _, tc := range cases {
+		input := testInput(t, tc.input)
+		value, err := input.ParseValue(tc.raw)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error for %q, got %v", input.Type, tc.raw, value)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error for %q: %s", input.Type, tc.raw, err)
+		} else if value != tc.want {
+			t.Errorf("%s: expected %v for %q, got %v", input.Type, tc.want, tc.raw, value)
+		}
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	cases := []struct {
+		input   string
+		value   any
+		want    string
+		wantErr bool
+	}{
+		{input: `{"name": "host", "type": "textfield"}`, value: "example.com", want: "example.com"},
+		{input: `{"name": "port", "type": "textfield"}`, value: 8080, want: "8080"},
+		{input: `{"name": "ratio", "type": "textfield"}`, value: 0.5, want: "0.5"},
+		{input: `{"name": "closed", "type": "checkbox"}`, value: true, want: "true"},
+		{input: `{"name": "closed", "type": "checkbox"}`, value: "false", want: "false"},
+		{input: `{"name": "closed", "type": "checkbox"}`, value: 1, wantErr: true},
+		{input: `{"name": "labels", "type": "textfield"}`, value: []any{"bug", "feature"}, want: `["bug","feature"]`},
+		{input: `{"name": "users", "type": "textfield"}`, value: map[string]any{"alice": "admin"}, want: `{"alice":"admin"}`},
+		{input: `{"name": "root", "type": "directory"}`, value: "/tmp", want: "/tmp"},
+	}
+
+	for _, tc := range cases {
+		input := testInput(t, tc.input)
+		encoded, err := input.EncodeValue(tc.value)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error for %v, got %s", input.Name, tc.value, encoded)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error for %v: %s", input.Name, tc.value, err)
+		} else if encoded != tc.want {
+			t.Errorf("%s: expected %s for %v, got %s", input.Name, tc.want, tc.value, encoded)
+		}
+	}
+
+	// File paths are resolved from the home directory
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := testInput(t, `{"name": "config", "type": "file"}`).EncodeValue("~/config.yml")
+	if err != nil || encoded != path.Join(home, "config.yml") {
+		t.Errorf("expected the path to be resolved, got %s, %v", encoded, err)
+	}
+}
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sunbeamlauncher/sunbeam/utils"
+)
+
+const DefaultRegistry = "https://raw.githubusercontent.com/sunbeamlauncher/sunbeam/main/registry/index.json"
+
+// RegistryIndex lists the extensions available for installation.
+type RegistryIndex struct {
+	Extensions []RegistryExtension `json:"extensions"`
+}
+
+type RegistryExtension struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Repo is the url of the git repository of the extension
+	Repo string `json:"repo"`
+	// Versions are sorted from the latest to the oldest
+	Versions []RegistryVersion `json:"versions"`
+}
+
+type RegistryVersion struct {
+	Version string `json:"version"`
+	// Commit is the git commit of the version
+	Commit string `json:"commit,omitempty"`
+	// Url points to an archive of the version, its content is verified with the sha256 checksum.
+	// The checksum only applies to the archive, the commit identifies the content on its own.
+	Url    string `json:"url,omitempty"`
+	Sha256 string `json:"sha256,omitempty"`
+}
+
+// registryNamePattern matches the names accepted by sunbeam extension install, which are passed to a shell.
+var registryNamePattern = regexp.MustCompile(`^[\w-]+$`)
+
+func (e RegistryExtension) Latest() (RegistryVersion, bool) {
+	if len(e.Versions) == 0 {
+		return RegistryVersion{}, false
+	}
+	return e.Versions[0], true
+}
+
+// Registry fetches the extensions of several indexes, the first index listing an extension wins.
+type Registry struct {
+	Urls []string
+	// CacheDir keeps a copy of the indexes, used when they cannot be fetched
+	CacheDir string
+	Client   *http.Client
+}
+
+func NewRegistry(urls []string, cacheDir string) Registry {
+	if len(urls) == 0 {
+		urls = []string{DefaultRegistry}
+	}
+
+	return Registry{
+		Urls:     urls,
+		CacheDir: cacheDir,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Extensions lists the extensions of all the indexes.
+// The indexes which cannot be fetched are skipped with a warning, an error is only returned if none of them can be fetched.
+func (r Registry) Extensions() ([]RegistryExtension, error) {
+	extensions := make([]RegistryExtension, 0)
+	seen := make(map[string]struct{})
+	var fetchErr error
+	var fetched int
+	for _, indexUrl := range r.Urls {
+		index, err := r.fetchIndex(indexUrl)
+		if err != nil {
+			log.Printf("skipping registry index: %s", err)
+			fetchErr = err
+			continue
+		}
+		fetched++
+
+		for _, extension := range index.Extensions {
+			if extension.Name == "" || extension.Repo == "" {
+				return nil, fmt.Errorf("invalid index %s: extensions require a name and a repo", indexUrl)
+			}
+			if !registryNamePattern.MatchString(extension.Name) {
+				return nil, fmt.Errorf("invalid index %s: invalid extension name %q", indexUrl, extension.Name)
+			}
+			for _, version := range extension.Versions {
+				// Commits are passed to git, they must not be mistaken for options
+				if strings.HasPrefix(version.Commit, "-") {
+					return nil, fmt.Errorf("invalid index %s: invalid commit %q for %s", indexUrl, version.Commit, extension.Name)
+				}
+			}
+
+			if _, ok := seen[extension.Name]; ok {
+				continue
+			}
+			seen[extension.Name] = struct{}{}
+			extensions = append(extensions, extension)
+		}
+	}
+
+	if fetched == 0 && fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	return extensions, nil
+}
+
+// Find returns the extension with the given name.
+func (r Registry) Find(name string) (RegistryExtension, error) {
+	extensions, err := r.Extensions()
+	if err != nil {
+		return RegistryExtension{}, err
+	}
+
+	for _, extension := range extensions {
+		if extension.Name == name {
+			return extension, nil
+		}
+	}
+
+	return RegistryExtension{}, fmt.Errorf("extension %s not found in the registry", name)
+}
+
+// FindRepo returns the extension hosted in the given repository, the urls are compared with NormalizeRepo.
+func (r Registry) FindRepo(repo string) (RegistryExtension, bool, error) {
+	extensions, err := r.Extensions()
+	if err != nil {
+		return RegistryExtension{}, false, err
+	}
+
+	for _, extension := range extensions {
+		if NormalizeRepo(extension.Repo) == NormalizeRepo(repo) {
+			return extension, true, nil
+		}
+	}
+
+	return RegistryExtension{}, false, nil
+}
+
+func (r Registry) fetchIndex(indexUrl string) (RegistryIndex, error) {
+	cachePath := r.cachePath(indexUrl)
+
+	content, err := r.read(indexUrl)
+	if err != nil {
+		// Fallback to the last fetched copy, e.g. when offline
+		cached, cacheErr := os.ReadFile(cachePath)
+		if cachePath == "" || cacheErr != nil {
+			return RegistryIndex{}, fmt.Errorf("failed to fetch index %s: %w", indexUrl, err)
+		}
+		content = cached
+	} else if cachePath != "" {
+		if err := os.MkdirAll(path.Dir(cachePath), 0755); err == nil {
+			os.WriteFile(cachePath, content, 0644)
+		}
+	}
+
+	var index RegistryIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return RegistryIndex{}, fmt.Errorf("failed to parse index %s: %w", indexUrl, err)
+	}
+
+	return index, nil
+}
+
+func (r Registry) cachePath(indexUrl string) string {
+	if r.CacheDir == "" {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(indexUrl))
+	return path.Join(r.CacheDir, hex.EncodeToString(hash[:])+".json")
+}
+
+// read supports http(s) and file urls, as well as local paths.
+func (r Registry) read(indexUrl string) ([]byte, error) {
+	u, err := url.Parse(indexUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		resp, err := r.Client.Get(indexUrl)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	case "file":
+		return os.ReadFile(u.Path)
+	case "":
+		indexPath, err := utils.ResolvePath(indexUrl)
+		if err != nil {
+			return nil, err
+		}
+		return os.ReadFile(indexPath)
+	default:
+		return nil, fmt.Errorf("unsupported index url: %s", indexUrl)
+	}
+}
+
+// NormalizeRepo strips the scheme, the credentials and the .git suffix of a repository url, so that clone urls can be compared.
+func NormalizeRepo(repo string) string {
+	repo = strings.TrimSpace(repo)
+	// scp-like syntax, e.g. git@github.com:owner/repo.git
+	if !strings.Contains(repo, "://") {
+		if user, rest, ok := strings.Cut(repo, "@"); ok && !strings.Contains(user, "/") {
+			repo = strings.Replace(rest, ":", "/", 1)
+		}
+	} else if u, err := url.Parse(repo); err == nil {
+		repo = u.Host + u.Path
+	}
+
+	repo = strings.TrimSuffix(repo, "/")
+	repo = strings.TrimSuffix(repo, ".git")
+	repo = strings.TrimSuffix(repo, "/")
+	return strings.ToLower(repo)
+}
@@ -0,0 +1,297 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/alessio/shellescape"
+)
+
+// ScriptCommand is the name of the implicit command of single-file extensions.
+const ScriptCommand = "run"
+
+const scriptManifestTimeout = 2 * time.Second
+
+// ErrNoScriptManifest is returned for the executables which are not single-file extensions, since they have no @sunbeam annotation.
+var ErrNoScriptManifest = errors.New("no sunbeam manifest")
+
+// ScriptManifest is the manifest of a single-file extension.
+// It is either embedded in the header comments of the script, or printed by the script when called with --sunbeam-manifest.
+type ScriptManifest struct {
+	Title           string                 `json:"title"`
+	Description     string                 `json:"description"`
+	Preferences     []ScriptInput          `json:"preferences"`
+	PreferencesJson string                 `json:"preferencesJson"`
+	Requirements    []ExtensionRequirement `json:"requirements"`
+
+	// Mode is the page type of the command output, the output is not displayed if it is empty
+	Mode        string        `json:"mode"`
+	OnSuccess   string        `json:"onSuccess"`
+	Interactive bool          `json:"interactive"`
+	Inputs      []ScriptInput `json:"inputs"`
+}
+
+// IsScriptExtension reports whether the file can be a single-file extension, only executable files are considered.
+func IsScriptExtension(fi os.FileInfo) bool {
+	return fi.Mode().IsRegular() && fi.Mode().Perm()&0111 != 0
+}
+
+// ParseScriptExtension reads the manifest of a single-file extension, from its header comments.
+// The script is only called with --sunbeam-manifest if its header contains the @sunbeam.manifest-command annotation.
+func ParseScriptExtension(extensionName string, scriptPath string) (Extension, error) {
+	document, manifestCommand, err := parseScriptHeader(scriptPath)
+	if err != nil {
+		return Extension{}, err
+	}
+
+	if manifestCommand {
+		if document, err = queryScriptManifest(scriptPath); err != nil {
+			return Extension{}, err
+		}
+	}
+
+	manifest, err := decodeScriptManifest(document)
+	if err != nil {
+		return Extension{}, fmt.Errorf("invalid manifest in %s: %w", scriptPath, err)
+	}
+
+	return manifest.Extension(extensionName, scriptPath)
+}
+
+// decodeScriptManifest validates the manifest against the schema of sunbeam.yml manifests, before decoding it.
+func decodeScriptManifest(document map[string]any) (ScriptManifest, error) {
+	if err := schema.Validate(manifestDocument(document)); err != nil {
+		return ScriptManifest{}, err
+	}
+
+	content, err := json.Marshal(document)
+	if err != nil {
+		return ScriptManifest{}, err
+	}
+
+	var manifest ScriptManifest
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&manifest); err != nil {
+		return ScriptManifest{}, err
+	}
+
+	return manifest, nil
+}
+
+// manifestDocument converts a script manifest to the equivalent sunbeam.yml manifest, with a single command.
+func manifestDocument(document map[string]any) map[string]any {
+	manifest := map[string]any{
+		"version": "1.0",
+	}
+	command := map[string]any{
+		"exec": "",
+	}
+
+	for key, value := range document {
+		switch key {
+		case "mode":
+			command["page"] = map[string]any{"type": value}
+		case "onSuccess", "interactive", "inputs":
+			command[key] = value
+		default:
+			// Unknown keys are reported by the schema
+			manifest[key] = value
+		}
+	}
+
+	manifest["commands"] = map[string]any{
+		ScriptCommand: command,
+	}
+
+	return manifest
+}
+
+// Extension converts the manifest to an extension with a single command, running the script with the inputs as arguments.
+func (m ScriptManifest) Extension(extensionName string, scriptPath string) (Extension, error) {
+	if m.Title == "" {
+		return Extension{}, fmt.Errorf("the manifest of %s requires a title", scriptPath)
+	}
+
+	args := []string{shellescape.Quote(scriptPath)}
+	for _, input := range m.Inputs {
+		if input.Name == "" || input.Type == "" {
+			return Extension{}, fmt.Errorf("the inputs of %s require a name and a type", scriptPath)
+		}
+		args = append(args, fmt.Sprintf("${{ %s }}", strings.ReplaceAll(input.Name, "-", "_")))
+	}
+
+	command := Command{
+		Name:        ScriptCommand,
+		Exec:        strings.Join(args, " "),
+		Description: m.Description,
+		Inputs:      m.Inputs,
+		Interactive: m.Interactive,
+		OnSuccess:   m.OnSuccess,
+	}
+
+	switch m.Mode {
+	case "":
+	case "list", "detail":
+		command.Page = Page{Type: m.Mode}
+		if command.OnSuccess == "" {
+			command.OnSuccess = "push-page"
+		}
+	default:
+		return Extension{}, fmt.Errorf("unknown mode %s in the manifest of %s, expected list or detail", m.Mode, scriptPath)
+	}
+
+	return Extension{
+		Name:            extensionName,
+		Title:           m.Title,
+		Description:     m.Description,
+		Root:            path.Dir(scriptPath),
+		ScriptFile:      scriptPath,
+		Preferences:     m.Preferences,
+		PreferencesJson: m.PreferencesJson,
+		Requirements:    m.Requirements,
+		RootItems: []RootItem{
+			{
+				Extension: extensionName,
+				Script:    ScriptCommand,
+				Title:     m.Title,
+				Subtitle:  m.Title,
+			},
+		},
+		Commands: map[string]Command{
+			ScriptCommand: command,
+		},
+	}, nil
+}
+
+// parseScriptHeader reads the @sunbeam.<key> annotations of the first comment block of the script, e.g.
+//
+//	#!/bin/sh
+//	# @sunbeam.title Say Hello
+//	# @sunbeam.mode detail
+//	# @sunbeam.input name textfield Name
+//
+// Inputs and preferences are declared as `<name> <type> [title]`.
+// The @sunbeam.manifest-command annotation replaces the other ones by the output of the script called with --sunbeam-manifest.
+func parseScriptHeader(scriptPath string) (document map[string]any, manifestCommand bool, err error) {
+	f, err := os.Open(scriptPath)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	document = make(map[string]any)
+	var annotated bool
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if lineNumber == 1 && strings.HasPrefix(line, "#!") {
+			continue
+		}
+
+		comment, isComment := trimCommentPrefix(line)
+		if !isComment {
+			break
+		}
+
+		if !strings.HasPrefix(comment, "@sunbeam.") {
+			continue
+		}
+		annotated = true
+
+		key, value, _ := strings.Cut(strings.TrimPrefix(comment, "@sunbeam."), " ")
+		value = strings.TrimSpace(value)
+		if key == "manifest-command" {
+			manifestCommand = true
+			continue
+		}
+
+		if err := setAnnotation(document, key, value); err != nil {
+			return nil, false, fmt.Errorf("%s:%d: %w", scriptPath, lineNumber, err)
+		}
+	}
+
+	// Binary files do not have a header, the scanner fails on their long lines
+	if err := scanner.Err(); err != nil && err != bufio.ErrTooLong {
+		return nil, false, err
+	}
+
+	if !annotated {
+		return nil, false, ErrNoScriptManifest
+	}
+
+	return document, manifestCommand, nil
+}
+
+func trimCommentPrefix(line string) (string, bool) {
+	for _, prefix := range []string{"#", "//", "--"} {
+		if strings.HasPrefix(line, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, prefix)), true
+		}
+	}
+	return "", false
+}
+
+// setAnnotation adds an annotation to the manifest document, the values are validated with the rest of the manifest.
+func setAnnotation(document map[string]any, key string, value string) error {
+	switch key {
+	case "title", "description", "mode", "onSuccess", "preferencesJson":
+		document[key] = value
+	case "interactive":
+		document[key] = value == "" || value == "true"
+	case "input", "preference":
+		fields := strings.Fields(value)
+		if len(fields) < 2 {
+			return fmt.Errorf("@sunbeam.%s requires a name and a type", key)
+		}
+
+		input := map[string]any{
+			"name":  fields[0],
+			"type":  fields[1],
+			"title": strings.Join(fields[2:], " "),
+		}
+		if input["title"] == "" {
+			input["title"] = fields[0]
+		}
+		if fields[1] == "checkbox" {
+			input["label"] = input["title"]
+		}
+
+		listKey := key + "s"
+		inputs, _ := document[listKey].([]any)
+		document[listKey] = append(inputs, input)
+	default:
+		return fmt.Errorf("unknown annotation @sunbeam.%s", key)
+	}
+
+	return nil
+}
+
+// queryScriptManifest runs the script with the --sunbeam-manifest flag, and parses the json manifest it prints.
+func queryScriptManifest(scriptPath string) (map[string]any, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), scriptManifestTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, scriptPath, "--sunbeam-manifest")
+	cmd.Dir = path.Dir(scriptPath)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s does not provide a manifest: %w", scriptPath, err)
+	}
+
+	var document map[string]any
+	if err := json.Unmarshal(output, &document); err != nil {
+		return nil, fmt.Errorf("invalid manifest printed by %s: %w", scriptPath, err)
+	}
+
+	return document, nil
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestParseScriptExtension(t *testing.T) {
+	cases := []struct {
+		name    string
+		script  string
+		wantErr bool
+		check   func(t *testing.T, extension Extension)
+	}{
+		{
+			name: "header",
+			script: `#!/bin/sh
+# @sunbeam.title Say Hello
+# @sunbeam.description Greets someone
+# @sunbeam.mode detail
+# @sunbeam.input name textfield Your Name
+# @sunbeam.input loud checkbox
+# @sunbeam.preference greeting textfield
+echo "hello $1"
+`,
+			check: func(t *testing.T, extension Extension) {
+				command := extension.Commands[ScriptCommand]
+				if extension.Title != "Say Hello" || extension.Description != "Greets someone" {
+					t.Errorf("unexpected extension: %+v", extension)
+				}
+				if command.Page.Type != "detail" || command.OnSuccess != "push-page" {
+					t.Errorf("unexpected command: %+v", command)
+				}
+				if len(command.Inputs) != 2 || command.Inputs[0].Title != "Your Name" || command.Inputs[1].Label != "loud" {
+					t.Errorf("unexpected inputs: %+v", command.Inputs)
+				}
+				if len(extension.Preferences) != 1 || extension.Preferences[0].Name != "greeting" {
+					t.Errorf("unexpected preferences: %+v", extension.Preferences)
+				}
+				if len(extension.RootItems) != 1 || extension.RootItems[0].Script != ScriptCommand {
+					t.Errorf("unexpected root items: %+v", extension.RootItems)
+				}
+			},
+		},
+		{
+			name: "other comment styles",
+			script: `#!/usr/bin/env node
+// @sunbeam.title From Node
+// @sunbeam.onSuccess copy-text
+console.log("hello")
+`,
+			check: func(t *testing.T, extension Extension) {
+				command := extension.Commands[ScriptCommand]
+				if extension.Title != "From Node" || command.OnSuccess != "copy-text" || command.Page.Type != "" {
+					t.Errorf("unexpected extension: %+v", extension)
+				}
+			},
+		},
+		{
+			name: "annotations after the header are ignored",
+			script: `#!/bin/sh
+# @sunbeam.title Header
+echo
+# @sunbeam.title Body
+`,
+			check: func(t *testing.T, extension Extension) {
+				if extension.Title != "Header" {
+					t.Errorf("got title %s", extension.Title)
+				}
+			},
+		},
+		{
+			name:    "no annotations",
+			script:  "#!/bin/sh\n# a regular script\necho\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing title",
+			script:  "#!/bin/sh\n# @sunbeam.mode list\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown annotation",
+			script:  "#!/bin/sh\n# @sunbeam.title Hello\n# @sunbeam.color red\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid input type",
+			script:  "#!/bin/sh\n# @sunbeam.title Hello\n# @sunbeam.input name number\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid onSuccess",
+			script:  "#!/bin/sh\n# @sunbeam.title Hello\n# @sunbeam.onSuccess explode\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid mode",
+			script:  "#!/bin/sh\n# @sunbeam.title Hello\n# @sunbeam.mode form\n",
+			wantErr: true,
+		},
+		{
+			name: "manifest command",
+			script: `#!/bin/sh
+# @sunbeam.manifest-command
+if [ "$1" = "--sunbeam-manifest" ]; then
+  echo '{"title": "Weather", "mode": "list", "inputs": [{"name": "city", "type": "dropdown", "title": "City", "data": [{"title": "Paris", "value": "paris"}]}]}'
+  exit 0
+fi
+`,
+			check: func(t *testing.T, extension Extension) {
+				command := extension.Commands[ScriptCommand]
+				if extension.Title != "Weather" || command.Page.Type != "list" {
+					t.Errorf("unexpected extension: %+v", extension)
+				}
+				if len(command.Inputs) != 1 || len(command.Inputs[0].Data) != 1 {
+					t.Errorf("unexpected inputs: %+v", command.Inputs)
+				}
+			},
+		},
+		{
+			name: "invalid json manifest",
+			script: `#!/bin/sh
+# @sunbeam.manifest-command
+echo '{"title": "Weather", "color": "red"}'
+`,
+			wantErr: true,
+		},
+		{
+			name: "failing manifest command",
+			script: `#!/bin/sh
+# @sunbeam.manifest-command
+exit 1
+`,
+			wantErr: true,
+		},
+	}
+
+	dir := t.TempDir()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			scriptPath := path.Join(dir, "script.sh")
+			if err := os.WriteFile(scriptPath, []byte(tc.script), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			extension, err := ParseScriptExtension("script", scriptPath)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if extension.Name != "script" || extension.ScriptFile != scriptPath {
+				t.Errorf("unexpected extension: %+v", extension)
+			}
+			tc.check(t, extension)
+		})
+	}
+}
+
+func TestLoadScriptExtensions(t *testing.T) {
+	root := t.TempDir()
+	marker := path.Join(root, "executed")
+	scripts := map[string]string{
+		"hello.sh": "#!/bin/sh\n# @sunbeam.title Hello\necho hello\n",
+		// Scripts without annotations must not be executed during discovery
+		"stray.sh": "#!/bin/sh\ntouch " + marker + "\n",
+	}
+	for name, content := range scripts {
+		if err := os.WriteFile(path.Join(root, name), []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var api Api
+	if err := api.LoadExtensions(root); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(api.Extensions) != 1 || api.Extensions[0].Name != "hello" {
+		t.Errorf("unexpected extensions: %+v", api.Extensions)
+	}
+	if _, err := os.Stat(marker); !errors.Is(err, os.ErrNotExist) {
+		t.Error("the stray script was executed")
+	}
+}
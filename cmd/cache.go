@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/sunbeamlauncher/sunbeam/app"
+)
+
+func NewCmdCache(api app.Api) *cobra.Command {
+	cacheCommand := &cobra.Command{
+		Use:     "cache",
+		Short:   "Inspect and clear the directories of the extensions",
+		GroupID: "core",
+	}
+
+	extensionArgs := make([]string, 0, len(api.Extensions))
+	for _, extension := range api.Extensions {
+		extensionArgs = append(extensionArgs, extension.Name)
+	}
+
+	cacheCommand.AddCommand(func() *cobra.Command {
+		return &cobra.Command{
+			Use:       "inspect [extension...]",
+			Short:     "Show the data, cache and state directories of the extensions",
+			ValidArgs: extensionArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				extensions, err := selectExtensions(api, args)
+				if err != nil {
+					return err
+				}
+
+				rows := make([][]string, 0, len(extensions))
+				for _, extension := range extensions {
+					dataDir, err := extension.DataDir()
+					if err != nil {
+						return err
+					}
+					cacheDir, err := extension.CacheDir()
+					if err != nil {
+						return err
+					}
+					stateDir, err := extension.StateDir()
+					if err != nil {
+						return err
+					}
+
+					size, err := app.DirSize(cacheDir)
+					if err != nil {
+						return err
+					}
+
+					rows = append(rows, []string{extension.Name, dataDir, cacheDir, stateDir, formatSize(size)})
+				}
+
+				writer := tablewriter.NewWriter(os.Stdout)
+				writer.SetBorder(false)
+				writer.SetColumnSeparator(" ")
+				writer.SetHeader([]string{"Extension", "Data", "Cache", "State", "Cache Size"})
+				writer.AppendBulk(rows)
+				writer.Render()
+
+				return nil
+			},
+		}
+	}())
+
+	cacheCommand.AddCommand(func() *cobra.Command {
+		return &cobra.Command{
+			Use:       "clear [extension...]",
+			Short:     "Clear the cache of the extensions, all of them if none is specified",
+			ValidArgs: extensionArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				extensions, err := selectExtensions(api, args)
+				if err != nil {
+					return err
+				}
+
+				for _, extension := range extensions {
+					cacheDir, err := extension.CacheDir()
+					if err != nil {
+						return err
+					}
+
+					if err := os.RemoveAll(cacheDir); err != nil {
+						return fmt.Errorf("failed to clear the cache of %s: %w", extension.Name, err)
+					}
+				}
+
+				return nil
+			},
+		}
+	}())
+
+	return cacheCommand
+}
+
+// selectExtensions returns the extensions matching the names, or all the installed extensions if no name is given.
+func selectExtensions(api app.Api, names []string) ([]app.Extension, error) {
+	if len(names) == 0 {
+		return api.Extensions, nil
+	}
+
+	extensions := make([]app.Extension, 0, len(names))
+	for _, name := range names {
+		extension, ok := findExtension(api, name)
+		if !ok {
+			return nil, fmt.Errorf("extension %s is not installed", name)
+		}
+		extensions = append(extensions, extension)
+	}
+
+	return extensions, nil
+}
+
+func findExtension(api app.Api, name string) (app.Extension, bool) {
+	for _, extension := range api.Extensions {
+		if extension.Name == name {
+			return extension, true
+		}
+	}
+	return app.Extension{}, false
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
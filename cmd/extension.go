@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -9,6 +11,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/otiai10/copy"
@@ -16,9 +19,10 @@ import (
 	"github.com/sunbeamlauncher/sunbeam/app"
 	"github.com/sunbeamlauncher/sunbeam/tui"
 	"github.com/sunbeamlauncher/sunbeam/utils"
+	"golang.org/x/term"
 )
 
-func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
+func NewCmdExtension(api app.Api, config *tui.Config, preferences *app.Preferences) *cobra.Command {
 	extensionCommand := &cobra.Command{
 		Use:     "extension",
 		Aliases: []string{"extensions", "ext"},
@@ -33,9 +37,19 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 
 	extensionCommand.AddCommand(func() *cobra.Command {
 		command := &cobra.Command{
-			Use:   "install <directory-or-url>",
-			Short: "Install a sunbeam extension from a local directory or a git repository",
-			Args:  cobra.ExactArgs(1),
+			Use:   "install <source>",
+			Short: "Install a sunbeam extension from a local directory, a git repository, an archive or a gist",
+			Long: `Install a sunbeam extension from a local directory, a git repository, an archive or a gist.
+
+Local directories and single-file scripts are symlinked, so that changes to the extension are picked up immediately.
+Archives can be .tar.gz or .zip files, either local or downloaded from an url, their checksum is verified with --sha256.
+Git repositories are installed at the latest commit of their default branch, unless another commit is selected with --ref.
+Gists must contain a sunbeam.yml manifest.`,
+			Example: `  sunbeam extension install --name file-browser https://github.com/pomdtr/sunbeam-file-browser
+  sunbeam extension install --name file-browser --ref <commit> https://github.com/pomdtr/sunbeam-file-browser
+  sunbeam extension install --name file-browser --sha256 <checksum> https://example.com/file-browser-1.0.0.tar.gz
+  sunbeam extension install --name hello https://gist.github.com/<user>/<id>`,
+			Args: cobra.ExactArgs(1),
 			PreRunE: func(cmd *cobra.Command, args []string) error {
 				extensionName, err := cmd.Flags().GetString("name")
 				if err != nil {
@@ -46,7 +60,7 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 					return fmt.Errorf("extension name must be specified with --name")
 				}
 
-				invalidName := []string{"clipboard", "extension", "open", "query", "run"}
+				invalidName := []string{"cache", "clipboard", "extension", "extensions", "preferences", "open", "query", "run"}
 				for _, name := range invalidName {
 					if extensionName == name {
 						return fmt.Errorf("extension name %s is reserved", extensionName)
@@ -74,9 +88,23 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 					return err
 				}
 
-				extensionRoot := args[0]
-				if _, err := os.Stat(extensionRoot); err == nil {
-					extensionRoot, err = filepath.Abs(extensionRoot)
+				checksum, err := cmd.Flags().GetString("sha256")
+				if err != nil {
+					return err
+				}
+
+				ref, err := cmd.Flags().GetString("ref")
+				if err != nil {
+					return err
+				}
+
+				source := args[0]
+				if checksum != "" && !utils.IsArchive(source) {
+					return fmt.Errorf("--sha256 is only supported for archives")
+				}
+
+				if fi, err := os.Stat(source); err == nil && fi.IsDir() {
+					extensionRoot, err := filepath.Abs(source)
 					if err != nil {
 						fmt.Fprintf(os.Stderr, "Failed to get absolute path for extension root: %s", err)
 						os.Exit(1)
@@ -97,17 +125,60 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 					return nil
 				}
 
+				if fi, err := os.Stat(source); err == nil && !utils.IsArchive(source) {
+					if !app.IsScriptExtension(fi) {
+						return fmt.Errorf("%s is not an executable script", source)
+					}
+
+					scriptPath, err := filepath.Abs(source)
+					if err != nil {
+						return err
+					}
+
+					if _, err := app.ParseScriptExtension(extensionName, scriptPath); err != nil {
+						return err
+					}
+
+					// The extension of the script is kept, so that editors can detect its language
+					symlinkTarget := path.Join(api.ExtensionRoot, extensionName+path.Ext(scriptPath))
+					if err := os.Symlink(scriptPath, symlinkTarget); err != nil {
+						return fmt.Errorf("failed to create symlink: %w", err)
+					}
+
+					fmt.Println("Installed extension", extensionName)
+					return nil
+				}
+
 				tmpDir, err := os.MkdirTemp(os.TempDir(), "sunbeam")
 				if err != nil {
 					return err
 				}
+				defer os.RemoveAll(tmpDir)
+
+				githubHost := utils.DefaultGHHost(config.GithubHost)
+				host, id, isGist := parseGistUrl(source, githubHost)
+				if ref != "" && (utils.IsArchive(source) || isGist) {
+					return fmt.Errorf("--ref is only supported for git repositories")
+				}
 
-				err = utils.GitClone(extensionRoot, tmpDir)
+				if utils.IsArchive(source) {
+					err = fetchArchive(source, checksum, tmpDir)
+				} else if isGist {
+					err = fetchGist(utils.NewGHClient(host), id, tmpDir)
+				} else {
+					// git clone requires an empty target directory
+					repoDir := path.Join(tmpDir, "repo")
+					err = utils.GitClone(source, repoDir)
+					if err == nil && ref != "" {
+						err = utils.NewGitClient(repoDir).Checkout(ref)
+					}
+				}
 				if err != nil {
 					return err
 				}
 
-				manifestPath := path.Join(tmpDir, "sunbeam.yml")
+				extensionRoot := sourceRoot(tmpDir)
+				manifestPath := path.Join(extensionRoot, "sunbeam.yml")
 				if _, err = os.Stat(manifestPath); os.IsNotExist(err) {
 					return fmt.Errorf("extension %s does not have a sunbeam.yml manifest", extensionName)
 				}
@@ -123,11 +194,7 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 
 				target := path.Join(api.ExtensionRoot, extensionName)
 				os.MkdirAll(path.Dir(target), 0755)
-				if err := copy.Copy(tmpDir, target); err != nil {
-					return err
-				}
-
-				if err := os.RemoveAll(tmpDir); err != nil {
+				if err := copy.Copy(extensionRoot, target); err != nil {
 					return err
 				}
 
@@ -137,21 +204,36 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 		}
 
 		command.Flags().StringP("name", "n", "", "Extension name")
+		command.Flags().String("sha256", "", "Expected sha256 checksum of the archive")
+		command.Flags().String("ref", "", "Git commit to install, defaults to the latest commit of the default branch")
 
 		return command
 	}())
 
 	extensionCommand.AddCommand(func() *cobra.Command {
-		return &cobra.Command{
+		command := &cobra.Command{
 			Use:       "remove",
 			ValidArgs: extensionArgs,
 			Short:     "Remove an installed extension",
+			Args:      cobra.ExactArgs(1),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				extensionPath := path.Join(api.ExtensionRoot, args[0])
-				if _, err := os.Stat(extensionPath); os.IsNotExist(err) {
+				// Only the installed extensions are removed, the name is never joined to the extension root as is
+				extension, ok := findExtension(api, args[0])
+				if !ok {
 					fmt.Fprintln(os.Stderr, "Extension not found")
 					os.Exit(1)
 				}
+				extensionPath := api.ExtensionPath(extension.Name)
+
+				if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+					confirmed, err := confirm(fmt.Sprintf("Remove extension %s?", args[0]))
+					if err != nil {
+						return err
+					}
+					if !confirmed {
+						return nil
+					}
+				}
 
 				if err := os.RemoveAll(extensionPath); err != nil {
 					fmt.Fprintln(os.Stderr, "Failed to remove extension")
@@ -162,6 +244,9 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 				return nil
 			},
 		}
+
+		command.Flags().BoolP("yes", "y", false, "Skip the confirmation")
+		return command
 	}())
 
 	extensionCommand.AddCommand(func() *cobra.Command {
@@ -178,8 +263,12 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 					return fmt.Errorf("extension %s is already installed", args[1])
 				}
 
-				oldPath := path.Join(api.ExtensionRoot, args[0])
+				oldPath := api.ExtensionPath(args[0])
 				newPath := path.Join(api.ExtensionRoot, args[1])
+				if oldPath != path.Join(api.ExtensionRoot, args[0]) {
+					// Single-file extensions are named after their script
+					newPath += path.Ext(oldPath)
+				}
 				if err := copy.Copy(oldPath, newPath); err != nil {
 					return fmt.Errorf("failed to rename extension: %s", err)
 				}
@@ -200,7 +289,7 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 			Args:      cobra.ExactArgs(1),
 			ValidArgs: extensionArgs,
 			RunE: func(cmd *cobra.Command, args []string) error {
-				extensionDir := path.Join(api.ExtensionRoot, args[0])
+				extensionDir := api.ExtensionPath(args[0])
 				fi, err := os.Lstat(extensionDir)
 				if os.IsNotExist(err) {
 					fmt.Fprintln(os.Stderr, "Extension not found")
@@ -211,20 +300,29 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 					return fmt.Errorf("cannot upgrade local extensions")
 				}
 
+				if !fi.IsDir() {
+					return fmt.Errorf("cannot upgrade single-file extensions")
+				}
+
 				gc := utils.NewGitClient(extensionDir)
 
-				currentVersion := gc.GetCurrentVersion()
-				latestVersion, err := gc.GetLatestVersion()
+				// Extensions listed in the registry are upgraded to the commit of their latest version
+				registryExtension, _, err := newRegistry(config, api.Dirs).FindRepo(gc.GetOrigin())
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Warning: failed to fetch the registry:", err)
+				}
+
+				latestVersion, err := targetCommit(registryExtension, gc)
 				if err != nil {
 					return err
 				}
 
-				if currentVersion == latestVersion {
-					fmt.Printf("Extension %s is already up to date", args[0])
+				if gc.GetCurrentVersion() == latestVersion {
+					fmt.Printf("Extension %s is already up to date\n", args[0])
 					return nil
 				}
 
-				if err := gc.Pull(); err != nil {
+				if err := gc.Checkout(latestVersion); err != nil {
 					return err
 				}
 
@@ -272,74 +370,129 @@ func NewCmdExtension(api app.Api, config *tui.Config) *cobra.Command {
 		}
 	}())
 
+	extensionCommand.AddCommand(func() *cobra.Command {
+		return &cobra.Command{
+			Use:   "search [query]",
+			Short: "Search the extensions of the registry",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				extensions, err := newRegistry(config, api.Dirs).Extensions()
+				if err != nil {
+					return err
+				}
+
+				var query string
+				if len(args) > 0 {
+					query = strings.ToLower(args[0])
+				}
+
+				repos := installedRepos(api)
+				rows := make([][]string, 0)
+				for _, extension := range extensions {
+					if !strings.Contains(strings.ToLower(extension.Name), query) && !strings.Contains(strings.ToLower(extension.Description), query) {
+						continue
+					}
+
+					// Extensions without versions are installed from their default branch
+					version := "latest"
+					if latest, ok := extension.Latest(); ok {
+						version = latest.Version
+					}
+
+					var status string
+					if _, ok, upgradable := registryStatus(extension, repos); upgradable {
+						status = "upgradable"
+					} else if ok {
+						status = "installed"
+					}
+
+					rows = append(rows, []string{extension.Name, extension.Description, version, status})
+				}
+
+				writer := tablewriter.NewWriter(os.Stdout)
+				writer.SetBorder(false)
+				writer.SetColumnSeparator(" ")
+				writer.SetHeader([]string{"Name", "Description", "Version", "Status"})
+				writer.AppendBulk(rows)
+				writer.Render()
+
+				return nil
+			},
+		}
+	}())
+
 	extensionCommand.AddCommand(func() *cobra.Command {
 		command := cobra.Command{
 			Use:   "browse",
-			Short: "Enter a UI for browsing and installing extensions",
+			Short: "Enter a UI for browsing and installing the extensions of the registry",
 			RunE: func(cmd *cobra.Command, args []string) (err error) {
-				client := utils.NewGHClient("github.com")
+				extensions, err := newRegistry(config, api.Dirs).Extensions()
 				if err != nil {
 					return err
 				}
-				res := struct {
-					Items []struct {
-						Name  string
-						Owner struct {
-							Login string
-						}
-						FullName    string `json:"full_name"`
-						Description string
-						HtmlURL     string `json:"html_url"`
-					}
-				}{}
 
-				err = client.Get("search/repositories?q=topic:sunbeam-extension", &res)
-				if err != nil {
+				if err := setupTUI(config, api.Dirs); err != nil {
 					return err
 				}
 
-				extensionItems := make([]tui.ListItem, len(res.Items))
-				for i, repo := range res.Items {
+				repos := installedRepos(api)
+				extensionItems := make([]tui.ListItem, len(extensions))
+				for i, extension := range extensions {
 					item := tui.ListItem{
 						Id:       strconv.Itoa(i),
-						Title:    fmt.Sprintf("%s/%s", repo.Owner.Login, repo.Name),
-						Subtitle: repo.Description,
+						Title:    extension.Name,
+						Subtitle: extension.Description,
 					}
 
-					if _, err := os.Stat(filepath.Join(api.ExtensionRoot, "github.com", repo.FullName)); err == nil {
-						item.Accessories = []string{
-							"Installed",
-						}
+					openRepo := tui.Action{
+						Title: "Open in Browser",
+						Cmd:   tui.NewOpenUrlCmd(extension.Repo),
+					}
 
+					installed, ok, upgradable := registryStatus(extension, repos)
+					switch {
+					case upgradable:
+						item.Accessories = []string{"Upgrade Available"}
 						item.Actions = []tui.Action{
 							{
-								Title: "Remove Extension",
-								Cmd:   tui.NewExecCmd(fmt.Sprintf("sunbeam extension remove %s", repo.Name)),
-							},
-							{
-								Title: "Open in Browser",
-								Cmd:   tui.NewOpenUrlCmd(repo.HtmlURL),
+								Title: "Upgrade Extension",
+								Cmd:   tui.NewExecCmd(fmt.Sprintf("sunbeam extension upgrade %s", installed.name)),
 							},
 						}
-					} else {
+					case ok:
+						item.Accessories = []string{"Installed"}
+					default:
+						if latest, ok := extension.Latest(); ok {
+							item.Accessories = []string{latest.Version}
+						}
 						item.Actions = []tui.Action{
 							{
 								Title: "Install Extension",
-								Cmd:   tui.NewExecCmd(fmt.Sprintf("sunbeam extension install %s --name %s", repo.HtmlURL, repo.Name)),
-							},
-							{
-								Title: "Open in Browser",
-								Cmd:   tui.NewOpenUrlCmd(repo.HtmlURL),
+								Cmd:   tui.NewExecCmd(installCommand(extension)),
 							},
+							openRepo,
 						}
 					}
 
+					if ok {
+						item.Actions = append(item.Actions,
+							tui.Action{
+								Title: "Remove Extension",
+								Cmd: tui.NewConfirmCmd(
+									fmt.Sprintf("Remove extension %s?", installed.name),
+									tui.NewExecCmd(fmt.Sprintf("sunbeam extension remove --yes %s", installed.name)),
+								),
+							},
+							openRepo,
+						)
+					}
+
 					extensionItems[i] = item
 				}
 
 				list := tui.NewList("Browse Extensions")
 				list.SetItems(extensionItems)
-				model := tui.NewModel(config)
+				model := tui.NewModel(config, api.Dirs, preferences)
 				model.SetRoot(list)
 
 				return tui.Draw(model)
@@ -367,3 +520,19 @@ func PostInstallHook(extension app.Extension) error {
 
 	return cmd.Run()
 }
+
+// confirm prompts the user for a confirmation on the terminal.
+func confirm(message string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, errors.New("confirmation required, use --yes to skip it")
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", message)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
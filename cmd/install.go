@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sunbeamlauncher/sunbeam/utils"
+)
+
+// fetchArchive downloads or copies an archive, verifies its checksum if one is provided, and extracts it in the target directory.
+func fetchArchive(source string, checksum string, target string) error {
+	archive, err := os.CreateTemp("", "sunbeam-*-"+path.Base(source))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	hash := sha256.New()
+	w := io.MultiWriter(archive, hash)
+	if isRemote(source) {
+		if err := downloadFile(source, w); err != nil {
+			return err
+		}
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+	}
+
+	if checksum != "" {
+		if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, checksum) {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", source, checksum, actual)
+		}
+	} else if isRemote(source) {
+		fmt.Fprintln(os.Stderr, "Warning: the archive checksum was not verified, use --sha256 to verify it")
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	return utils.ExtractArchive(archive.Name(), target)
+}
+
+func isRemote(source string) bool {
+	return strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://")
+}
+
+func downloadFile(url string, w io.Writer) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// parseGistUrl extracts the host and the id of a gist from its url.
+// Gists are hosted on gist.github.com, or under the /gist path of GitHub Enterprise instances.
+func parseGistUrl(source string, githubHost string) (host string, id string, ok bool) {
+	u, err := url.Parse(source)
+	if err != nil || !isRemote(source) {
+		return "", "", false
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	switch {
+	case strings.EqualFold(u.Host, "gist.github.com"):
+		host = "github.com"
+	case strings.EqualFold(u.Host, githubHost) && segments[0] == "gist":
+		host = u.Host
+		segments = segments[1:]
+	default:
+		return "", "", false
+	}
+
+	if len(segments) == 0 || segments[len(segments)-1] == "" {
+		return "", "", false
+	}
+
+	return host, strings.TrimSuffix(segments[len(segments)-1], ".git"), true
+}
+
+type gist struct {
+	Files map[string]struct {
+		Content   string `json:"content"`
+		Truncated bool   `json:"truncated"`
+		RawUrl    string `json:"raw_url"`
+	} `json:"files"`
+}
+
+// fetchGist writes the files of a gist in the target directory, the gist must contain a sunbeam.yml manifest.
+func fetchGist(client utils.RestClient, id string, target string) error {
+	var res gist
+	if err := client.Get(fmt.Sprintf("gists/%s", id), &res); err != nil {
+		if utils.IsNotFound(err) {
+			return fmt.Errorf("gist %s not found", id)
+		}
+		return err
+	}
+
+	if _, ok := res.Files["sunbeam.yml"]; !ok {
+		return fmt.Errorf("gist %s does not contain a sunbeam.yml manifest", id)
+	}
+
+	for name, file := range res.Files {
+		if name != filepath.Base(name) || name == ".." {
+			return fmt.Errorf("invalid gist filename: %s", name)
+		}
+
+		f, err := os.OpenFile(path.Join(target, name), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+
+		// The content of large files is not included in the response
+		if file.Truncated {
+			err = client.Download(file.RawUrl, f)
+		} else {
+			_, err = f.WriteString(file.Content)
+		}
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		// Gists do not keep the file modes, so scripts are detected by their shebang
+		if strings.HasPrefix(file.Content, "#!") {
+			if err := os.Chmod(path.Join(target, name), 0755); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sourceRoot returns the directory containing the manifest.
+// Release archives usually wrap their content in a single top level directory.
+func sourceRoot(dir string) string {
+	if _, err := os.Stat(path.Join(dir, "sunbeam.yml")); err == nil {
+		return dir
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+
+	return path.Join(dir, entries[0].Name())
+}
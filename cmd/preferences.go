@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/sunbeamlauncher/sunbeam/app"
+)
+
+func NewCmdPreferences(api app.Api, preferences *app.Preferences) *cobra.Command {
+	preferencesCommand := &cobra.Command{
+		Use:     "preferences",
+		Aliases: []string{"prefs"},
+		Short:   "Manage the preferences of the extensions",
+		GroupID: "core",
+	}
+
+	extensionArgs := make([]string, 0, len(api.Extensions))
+	for _, extension := range api.Extensions {
+		extensionArgs = append(extensionArgs, extension.Name)
+	}
+
+	preferencesCommand.AddCommand(func() *cobra.Command {
+		command := &cobra.Command{
+			Use:   "get <extension> <name>",
+			Short: "Print the value of a preference",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				script, _ := cmd.Flags().GetString("command")
+				if _, err := findPreferenceInput(api, args[0], script, args[1]); err != nil {
+					return err
+				}
+
+				preference, ok := preferences.Get(args[0], script, args[1])
+				if !ok {
+					return fmt.Errorf("preference %s is not set", args[1])
+				}
+
+				fmt.Println(preference.Value)
+				return nil
+			},
+		}
+
+		command.Flags().StringP("command", "c", "", "command defining the preference, the extension preferences are used as a fallback")
+		return command
+	}())
+
+	preferencesCommand.AddCommand(func() *cobra.Command {
+		command := &cobra.Command{
+			Use:   "set <extension> <name> <value>",
+			Short: "Set the value of a preference, in the active profile if any",
+			Args:  cobra.ExactArgs(3),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				script, _ := cmd.Flags().GetString("command")
+				input, err := findPreferenceInput(api, args[0], script, args[1])
+				if err != nil {
+					return err
+				}
+
+				value, err := input.ParseValue(args[2])
+				if err != nil {
+					return err
+				}
+
+				return preferences.Set(app.Preference{
+					Name:      args[1],
+					Script:    script,
+					Extension: args[0],
+					Value:     value,
+				})
+			},
+		}
+
+		command.Flags().StringP("command", "c", "", "command defining the preference")
+		return command
+	}())
+
+	preferencesCommand.AddCommand(func() *cobra.Command {
+		command := &cobra.Command{
+			Use:   "unset <extension> <name>",
+			Short: "Remove the value of a preference, from the active profile if any",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				script, _ := cmd.Flags().GetString("command")
+				ok, err := preferences.Unset(args[0], script, args[1])
+				if err != nil {
+					return err
+				}
+
+				if !ok {
+					return fmt.Errorf("preference %s is not set", args[1])
+				}
+				return nil
+			},
+		}
+
+		command.Flags().StringP("command", "c", "", "command defining the preference")
+		return command
+	}())
+
+	preferencesCommand.AddCommand(func() *cobra.Command {
+		return &cobra.Command{
+			Use:       "list [extension]",
+			Short:     "List the preferences, passwords are masked",
+			Aliases:   []string{"ls"},
+			Args:      cobra.MaximumNArgs(1),
+			ValidArgs: extensionArgs,
+			Run: func(cmd *cobra.Command, args []string) {
+				var extension string
+				if len(args) > 0 {
+					extension = args[0]
+				}
+
+				rows := make([][]string, 0)
+				for _, preference := range preferences.List(extension) {
+					value := fmt.Sprint(preference.Value)
+					if input, err := findPreferenceInput(api, preference.Extension, preference.Script, preference.Name); err == nil && input.Type == "password" {
+						value = "********"
+					}
+
+					rows = append(rows, []string{preference.Extension, preference.Script, preference.Name, value, preference.Scope})
+				}
+
+				writer := tablewriter.NewWriter(os.Stdout)
+				writer.SetBorder(false)
+				writer.SetColumnSeparator(" ")
+				writer.SetHeader([]string{"Extension", "Command", "Name", "Value", "Scope"})
+				writer.AppendBulk(rows)
+				writer.Render()
+			},
+		}
+	}())
+
+	preferencesCommand.AddCommand(func() *cobra.Command {
+		return &cobra.Command{
+			Use:       "export [extension]",
+			Short:     "Export the preferences as json",
+			Args:      cobra.MaximumNArgs(1),
+			ValidArgs: extensionArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var extension string
+				if len(args) > 0 {
+					extension = args[0]
+				}
+
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(preferences.List(extension))
+			},
+		}
+	}())
+
+	preferencesCommand.AddCommand(func() *cobra.Command {
+		return &cobra.Command{
+			Use:   "trust [directory]",
+			Short: "Trust the closest .sunbeam.yml file, so that its preferences and profile are applied",
+			Long: `Trust the closest .sunbeam.yml file, from the directory to the root of the filesystem.
+The directory defaults to the working directory.
+
+The file must be trusted again once it is modified, since it can override the preferences and select the profile.`,
+			Args: cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				configPath, err := directoryConfigPath(args)
+				if err != nil {
+					return err
+				}
+
+				trusted, err := app.LoadTrustedConfigs(api.Dirs.TrustedConfigs())
+				if err != nil {
+					return err
+				}
+
+				if err := trusted.Trust(configPath); err != nil {
+					return err
+				}
+
+				fmt.Println("Trusted", configPath)
+				return nil
+			},
+		}
+	}())
+
+	preferencesCommand.AddCommand(func() *cobra.Command {
+		return &cobra.Command{
+			Use:   "untrust [directory]",
+			Short: "Stop applying the closest .sunbeam.yml file",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				configPath, err := directoryConfigPath(args)
+				if err != nil {
+					return err
+				}
+
+				trusted, err := app.LoadTrustedConfigs(api.Dirs.TrustedConfigs())
+				if err != nil {
+					return err
+				}
+
+				ok, err := trusted.Untrust(configPath)
+				if err != nil {
+					return err
+				}
+
+				if !ok {
+					return fmt.Errorf("%s is not trusted", configPath)
+				}
+				return nil
+			},
+		}
+	}())
+
+	return preferencesCommand
+}
+
+// directoryConfigPath returns the path of the closest directory config, from the directory given as argument or the working directory.
+func directoryConfigPath(args []string) (string, error) {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	configPath, err := app.FindDirectoryConfig(dir)
+	if err != nil {
+		return "", err
+	}
+
+	if configPath == "" {
+		return "", fmt.Errorf("no %s file found in %s or its parents", app.DirectoryConfigName, dir)
+	}
+	return configPath, nil
+}
+
+// findPreferenceInput returns the declaration of a preference, from the command if one is given.
+func findPreferenceInput(api app.Api, extensionName string, script string, name string) (app.ScriptInput, error) {
+	extension, ok := findExtension(api, extensionName)
+	if !ok {
+		return app.ScriptInput{}, fmt.Errorf("extension %s is not installed", extensionName)
+	}
+
+	inputs := extension.Preferences
+	if script != "" {
+		command, ok := extension.Commands[script]
+		if !ok {
+			return app.ScriptInput{}, fmt.Errorf("extension %s has no command %s", extensionName, script)
+		}
+		inputs = command.Preferences
+	}
+
+	for _, input := range inputs {
+		if input.Name == name {
+			return input, nil
+		}
+	}
+
+	if script != "" {
+		return app.ScriptInput{}, fmt.Errorf("command %s of extension %s has no preference %s", script, extensionName, name)
+	}
+	return app.ScriptInput{}, fmt.Errorf("extension %s has no preference %s", extensionName, name)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alessio/shellescape"
+	"github.com/sunbeamlauncher/sunbeam/app"
+	"github.com/sunbeamlauncher/sunbeam/tui"
+	"github.com/sunbeamlauncher/sunbeam/utils"
+)
+
+func newRegistry(config *tui.Config, dirs utils.Dirs) app.Registry {
+	return app.NewRegistry(config.Registries, dirs.RegistryCache())
+}
+
+// installedExtension is an extension installed from a git repository.
+type installedExtension struct {
+	name   string
+	root   string
+	commit string
+}
+
+// installedRepos maps the repositories of the installed extensions to their installation.
+// Local and single-file extensions are not tracked, since they are not installed from a repository.
+func installedRepos(api app.Api) map[string]installedExtension {
+	repos := make(map[string]installedExtension)
+	for _, extension := range api.Extensions {
+		if extension.ScriptFile != "" {
+			continue
+		}
+
+		fi, err := os.Lstat(extension.Root)
+		if err != nil || IsLocalExtension(fi) {
+			continue
+		}
+
+		gc := utils.NewGitClient(extension.Root)
+		origin := gc.GetOrigin()
+		if origin == "" {
+			continue
+		}
+
+		repos[app.NormalizeRepo(origin)] = installedExtension{
+			name:   extension.Name,
+			root:   extension.Root,
+			commit: gc.GetCurrentVersion(),
+		}
+	}
+
+	return repos
+}
+
+// targetCommit returns the commit an extension should be upgraded to.
+// It is the commit of the latest version listed in the registry, or the latest commit of the default branch for the extensions without versions.
+// The default branch is fetched from the remote, so it is only used by the upgrade command.
+func targetCommit(extension app.RegistryExtension, gc *utils.GitClient) (string, error) {
+	if latest, ok := extension.Latest(); ok && latest.Commit != "" {
+		return latest.Commit, nil
+	}
+
+	return gc.GetLatestVersion()
+}
+
+// registryStatus reports whether an extension of the registry is installed, and if a newer version is available.
+// Only the commits of the index are compared, the extensions without versions are never reported as upgradable.
+func registryStatus(extension app.RegistryExtension, repos map[string]installedExtension) (installed installedExtension, ok bool, upgradable bool) {
+	installed, ok = repos[app.NormalizeRepo(extension.Repo)]
+	if !ok {
+		return installedExtension{}, false, false
+	}
+
+	latest, hasLatest := extension.Latest()
+	if !hasLatest || latest.Commit == "" {
+		return installed, true, false
+	}
+
+	return installed, true, latest.Commit != installed.commit
+}
+
+// installCommand returns the command installing the latest version of an extension of the registry.
+// The commit of the version is checked out if it is known, otherwise its archive is installed.
+func installCommand(extension app.RegistryExtension) string {
+	latest, ok := extension.Latest()
+	switch {
+	case ok && latest.Commit != "":
+		return fmt.Sprintf("sunbeam extension install %s --name %s --ref %s", shellescape.Quote(extension.Repo), shellescape.Quote(extension.Name), shellescape.Quote(latest.Commit))
+	case ok && latest.Url != "" && latest.Sha256 != "":
+		return fmt.Sprintf("sunbeam extension install %s --name %s --sha256 %s", shellescape.Quote(latest.Url), shellescape.Quote(extension.Name), shellescape.Quote(latest.Sha256))
+	default:
+		return fmt.Sprintf("sunbeam extension install %s --name %s", shellescape.Quote(extension.Repo), shellescape.Quote(extension.Name))
+	}
+}
@@ -4,39 +4,63 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/sunbeamlauncher/sunbeam/app"
 	"github.com/sunbeamlauncher/sunbeam/tui"
+	"github.com/sunbeamlauncher/sunbeam/utils"
 )
 
-func NewCmdRun(config *tui.Config) *cobra.Command {
+func NewCmdRun(config *tui.Config, dirs utils.Dirs, preferences *app.Preferences) *cobra.Command {
 	runCmd := &cobra.Command{
-		Use:     "run <extension-root>",
-		Short:   "Run an extension from a directory",
+		Use:     "run <extension-root-or-script>",
+		Short:   "Run an extension from a directory or a single-file script",
 		GroupID: "core",
 		Args:    cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 
 			extensionRoot := args[0]
-			if fi, err := os.Stat(extensionRoot); err != nil || !fi.IsDir() {
+			fi, err := os.Stat(extensionRoot)
+			if err != nil {
 				fmt.Fprintf(os.Stderr, "Directory %s does not exist\n", extensionRoot)
 				os.Exit(1)
 			}
 
-			manifestPath := filepath.Join(extensionRoot, "sunbeam.yml")
-			if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
-				fmt.Fprintf(os.Stderr, "Directory %s is not a sunbeam extension\n", extensionRoot)
-				os.Exit(1)
+			extensionRoot, err = filepath.Abs(extensionRoot)
+			if err != nil {
+				return err
 			}
 
-			extension, err := app.ParseManifest(manifestPath, manifestPath)
-			if err != nil {
-				fmt.Fprintln(os.Stderr, "Failed to parse manifest:", err)
-				os.Exit(1)
+			var extension app.Extension
+			if fi.IsDir() {
+				manifestPath := filepath.Join(extensionRoot, "sunbeam.yml")
+				if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+					fmt.Fprintf(os.Stderr, "Directory %s is not a sunbeam extension\n", extensionRoot)
+					os.Exit(1)
+				}
+
+				extension, err = app.ParseManifest(filepath.Base(extensionRoot), manifestPath)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Failed to parse manifest:", err)
+					os.Exit(1)
+				}
+				extension.Root = extensionRoot
+			} else {
+				name := strings.TrimSuffix(filepath.Base(extensionRoot), filepath.Ext(extensionRoot))
+				extension, err = app.ParseScriptExtension(name, extensionRoot)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Failed to parse script:", err)
+					os.Exit(1)
+				}
+			}
+			extension.Dirs = dirs
+
+			if err := setupTUI(config, dirs); err != nil {
+				return err
 			}
 
-			model := tui.NewModel(config, extension)
+			model := tui.NewModel(config, dirs, preferences, extension)
 
 			return tui.Draw(model)
 		},
@@ -1,15 +1,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"path"
+	"regexp"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	"github.com/sunbeamlauncher/sunbeam/app"
 	"github.com/sunbeamlauncher/sunbeam/tui"
+	"github.com/sunbeamlauncher/sunbeam/utils"
 	cobracompletefig "github.com/withfig/autocomplete-tools/integrations/cobra"
 )
 
@@ -30,41 +33,230 @@ func parseConfig(configRoot string) (*tui.Config, error) {
 	return &config, err
 }
 
+// standaloneCommands do not use the installed extensions, so they are not loaded.
+var standaloneCommands = map[string]bool{
+	"completion": true,
+	"listen":     true,
+	"query":      true,
+	"run":        true,
+}
+
+// commandsWithoutPreferences do not run extensions, so the config of the working directory is not applied.
+// It is kept for run, which runs an extension with the preferences of the directory.
+var commandsWithoutPreferences = map[string]bool{
+	"completion": true,
+	"listen":     true,
+	"query":      true,
+}
+
+// coreCommands use all the installed extensions, so their name is not looked up as an extension.
+var coreCommands = map[string]bool{
+	"cache":       true,
+	"docs":        true,
+	"ext":         true,
+	"extension":   true,
+	"extensions":  true,
+	"help":        true,
+	"preferences": true,
+	"prefs":       true,
+}
+
+// parseGlobalFlags extracts the --config-dir and --profile flags from the arguments, since the config is loaded before the commands are built.
+// The remaining positional arguments are returned, the first one names the invoked command.
+// The other flags are not known yet, so they are skipped without consuming the next argument.
+func parseGlobalFlags(args []string) (configDir string, profile string, positional []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return configDir, profile, append(positional, args[i+1:]...)
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		var target *string
+		switch name {
+		case "--config-dir":
+			target = &configDir
+		case "--profile":
+			target = &profile
+		default:
+			if !strings.HasPrefix(arg, "-") || arg == "-" {
+				positional = append(positional, arg)
+			}
+			continue
+		}
+
+		if !hasValue {
+			if i+1 == len(args) {
+				break
+			}
+			i++
+			value = args[i]
+		}
+		*target = value
+	}
+
+	return configDir, profile, positional
+}
+
+// loadPreferences loads the global preferences, overridden by the ones of the profile and of the working directory, if useDirectory is set.
+// The profile defaults to SUNBEAM_PROFILE, then to the profile of the working directory.
+func loadPreferences(dirs utils.Dirs, profile string, useDirectory bool) (*app.Preferences, error) {
+	preferences, err := app.LoadPreferences(dirs.Preferences())
+	if err != nil {
+		return nil, err
+	}
+
+	var directoryConfig app.DirectoryConfig
+	if useDirectory {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		directoryConfig, err = loadDirectoryConfig(dirs, cwd)
+		if err != nil {
+			return nil, err
+		}
+		preferences.UseDirectory(directoryConfig)
+	}
+
+	if profile == "" {
+		profile = os.Getenv("SUNBEAM_PROFILE")
+	}
+	if profile == "" {
+		profile = directoryConfig.Profile
+	}
+	if profile == "" {
+		return preferences, nil
+	}
+
+	if !regexp.MustCompile(`^[\w-]+$`).MatchString(profile) {
+		return nil, fmt.Errorf("invalid profile name %q, only alphanumeric characters, dashes and underscores are allowed", profile)
+	}
+
+	if err := preferences.UseProfile(dirs.ProfilePreferences(profile)); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+// loadDirectoryConfig loads the closest .sunbeam.yml file, untrusted files are ignored with a warning.
+func loadDirectoryConfig(dirs utils.Dirs, dir string) (app.DirectoryConfig, error) {
+	configPath, err := app.FindDirectoryConfig(dir)
+	if err != nil || configPath == "" {
+		return app.DirectoryConfig{}, err
+	}
+
+	trusted, err := app.LoadTrustedConfigs(dirs.TrustedConfigs())
+	if err != nil {
+		return app.DirectoryConfig{}, err
+	}
+
+	directoryConfig, err := app.LoadDirectoryConfig(configPath, trusted)
+	if errors.Is(err, app.ErrUntrustedDirectoryConfig) {
+		fmt.Fprintf(os.Stderr, "Warning: %s is not trusted, run `sunbeam preferences trust` to apply it\n", configPath)
+		return app.DirectoryConfig{}, nil
+	}
+
+	return directoryConfig, err
+}
+
+// setupTUI applies the keymap and the theme selected in the config.
+// It must only be called before drawing the UI, since resolving the theme can query the background color of the terminal.
+func setupTUI(config *tui.Config, dirs utils.Dirs) error {
+	keymap, err := tui.NewKeyMap(config.Keymap)
+	if err != nil {
+		return fmt.Errorf("invalid keymap: %w", err)
+	}
+	tui.SetKeyMap(keymap)
+
+	themes, err := tui.LoadThemes(dirs.Themes())
+	if err != nil {
+		return err
+	}
+	for name, theme := range config.Themes {
+		themes[name] = theme
+	}
+	config.Themes = themes
+
+	theme, err := tui.ResolveTheme(config)
+	if err != nil {
+		return fmt.Errorf("invalid theme: %w", err)
+	}
+	tui.SetTheme(theme)
+
+	return nil
+}
+
 func Execute(version string) (err error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, profile, positional := parseGlobalFlags(os.Args[1:])
+	dirs, err := utils.ResolveDirs(configDir)
+	if err != nil {
+		return err
+	}
+
+	config, err := parseConfig(dirs.Config)
 	if err != nil {
 		return err
 	}
 
-	config, err := parseConfig(path.Join(homeDir, ".config", "sunbeam"))
+	var invoked string
+	if len(positional) > 0 {
+		invoked = positional[0]
+	}
+
+	preferences, err := loadPreferences(dirs, profile, !commandsWithoutPreferences[invoked])
 	if err != nil {
 		return err
 	}
 
-	extensionRoot := path.Join(homeDir, ".local", "share", "sunbeam", "extensions")
+	extensionRoot := dirs.Extensions()
 	if _, err := os.Stat(extensionRoot); os.IsNotExist(err) {
 		if err := os.MkdirAll(extensionRoot, 0755); err != nil {
 			return err
 		}
 	}
 
-	api := app.Api{}
-	err = api.LoadExtensions(extensionRoot)
-	if err != nil {
-		return err
+	api := app.Api{
+		Dirs:          dirs,
+		ExtensionRoot: extensionRoot,
+		Cache:         app.LoadManifestCache(dirs.ManifestCache(), version),
+	}
+
+	if !standaloneCommands[invoked] {
+		// When an extension is invoked, it is the only one to be loaded and registered
+		if invoked != "" && !coreCommands[invoked] {
+			if err := api.LoadExtensions(extensionRoot, invoked); err != nil {
+				return err
+			}
+		}
+
+		if len(api.Extensions) == 0 {
+			if err := api.LoadExtensions(extensionRoot); err != nil {
+				return err
+			}
+		}
 	}
 
 	// rootCmd represents the base command when called without any subcommands
 	var rootCmd = &cobra.Command{
-		Use:          "sunbeam",
-		Short:        "Command Line Launcher",
-		SilenceUsage: true,
-		Version:      version,
+		Use:           "sunbeam",
+		Short:         "Command Line Launcher",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Version:       version,
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
-			model := tui.NewModel(config, api.Extensions...)
+			if err := setupTUI(config, dirs); err != nil {
+				return err
+			}
+
+			model := tui.NewModel(config, dirs, preferences, api.Extensions...)
 			return tui.Draw(model)
 		},
 	}
+	rootCmd.PersistentFlags().String("config-dir", "", "directory containing the config file, defaults to $XDG_CONFIG_HOME/sunbeam")
+	rootCmd.PersistentFlags().String("profile", "", "profile overriding the global preferences, defaults to $SUNBEAM_PROFILE")
 
 	rootCmd.AddGroup(&cobra.Group{
 		Title: "Core Commands",
@@ -75,9 +267,11 @@ func Execute(version string) (err error) {
 	})
 
 	// Core Commands
-	rootCmd.AddCommand(NewCmdExtension(api, config))
+	rootCmd.AddCommand(NewCmdExtension(api, config, preferences))
+	rootCmd.AddCommand(NewCmdCache(api))
+	rootCmd.AddCommand(NewCmdPreferences(api, preferences))
 	rootCmd.AddCommand(NewCmdQuery())
-	rootCmd.AddCommand(NewCmdRun(config))
+	rootCmd.AddCommand(NewCmdRun(config, dirs, preferences))
 	rootCmd.AddCommand(NewCmdListen())
 	rootCmd.AddCommand(NewCmdDocs())
 	rootCmd.AddCommand(cobracompletefig.CreateCompletionSpecCommand())
@@ -85,20 +279,24 @@ func Execute(version string) (err error) {
 	if os.Getenv("DISABLE_EXTENSIONS") == "" {
 		// Extension Commands
 		for _, extension := range api.Extensions {
-			rootCmd.AddCommand(NewExtensionCommand(extension, config))
+			rootCmd.AddCommand(NewExtensionCommand(extension, config, dirs, preferences))
 		}
 	}
 
 	return rootCmd.Execute()
 }
 
-func NewExtensionCommand(extension app.Extension, config *tui.Config) *cobra.Command {
+func NewExtensionCommand(extension app.Extension, config *tui.Config, dirs utils.Dirs, preferences *app.Preferences) *cobra.Command {
 	extensionCmd := &cobra.Command{
 		Use:     extension.Name,
 		GroupID: "extension",
 		Short:   extension.Description,
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
-			root := tui.NewModel(config, extension)
+			if err := setupTUI(config, dirs); err != nil {
+				return err
+			}
+
+			root := tui.NewModel(config, dirs, preferences, extension)
 			err = tui.Draw(root)
 			if err != nil {
 				return fmt.Errorf("could not run extension: %w", err)
@@ -136,8 +334,16 @@ func NewExtensionCommand(extension app.Extension, config *tui.Config) *cobra.Com
 
 				}
 
-				model := tui.NewModel(config, extension)
-				runner := tui.NewScriptRunner(extension, script, with)
+				if yes, _ := cmd.Flags().GetBool("yes"); yes {
+					script.Confirm = app.Confirm{}
+				}
+
+				if err := setupTUI(config, dirs); err != nil {
+					return err
+				}
+
+				model := tui.NewModel(config, dirs, preferences, extension)
+				runner := tui.NewScriptRunner(extension, script, with, preferences)
 				model.SetRoot(runner)
 
 				err = tui.Draw(model)
@@ -165,6 +371,10 @@ func NewExtensionCommand(extension app.Extension, config *tui.Config) *cobra.Com
 			}
 		}
 
+		if script.Confirm.Required {
+			scriptCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation")
+		}
+
 		extensionCmd.AddCommand(scriptCmd)
 	}
 
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/sunbeamlauncher/sunbeam/app"
+	"github.com/sunbeamlauncher/sunbeam/tui"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	cases := []struct {
+		args       []string
+		configDir  string
+		profile    string
+		positional []string
+	}{
+		{args: []string{}},
+		{args: []string{"jira", "list-issues"}, positional: []string{"jira", "list-issues"}},
+		{args: []string{"--profile", "work", "jira"}, profile: "work", positional: []string{"jira"}},
+		{args: []string{"jira", "--profile=work", "--config-dir", "/tmp/sunbeam"}, configDir: "/tmp/sunbeam", profile: "work", positional: []string{"jira"}},
+		// Unknown flags do not consume the invoked command
+		{args: []string{"--foo", "jira"}, positional: []string{"jira"}},
+		{args: []string{"-v", "query", "-n", "."}, positional: []string{"query", "."}},
+		{args: []string{"run", "--", "--profile", "work"}, positional: []string{"run", "--profile", "work"}},
+		{args: []string{"--profile"}},
+	}
+
+	for _, tc := range cases {
+		configDir, profile, positional := parseGlobalFlags(tc.args)
+		if configDir != tc.configDir || profile != tc.profile || (len(positional) != 0 || len(tc.positional) != 0) && !reflect.DeepEqual(positional, tc.positional) {
+			t.Errorf("parseGlobalFlags(%q) = %q, %q, %q, expected %q, %q, %q", tc.args, configDir, profile, positional, tc.configDir, tc.profile, tc.positional)
+		}
+	}
+}
+
+func TestCoreCommands(t *testing.T) {
+	api := app.Api{}
+	for _, command := range []*cobra.Command{
+		NewCmdExtension(api, &tui.Config{}, nil),
+		NewCmdCache(api),
+		NewCmdPreferences(api, nil),
+		NewCmdDocs(),
+	} {
+		for _, name := range append([]string{command.Name()}, command.Aliases...) {
+			if !coreCommands[name] {
+				t.Errorf("%s is not listed in the core commands", name)
+			}
+		}
+	}
+
+	for name := range commandsWithoutPreferences {
+		if !standaloneCommands[name] {
+			t.Errorf("%s skips the directory config but loads the extensions", name)
+		}
+	}
+}
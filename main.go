@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/sunbeamlauncher/sunbeam/cmd"
@@ -11,6 +12,7 @@ var version = "dev"
 func main() {
 	err := cmd.Execute(version)
 	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/alessio/shellescape"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -18,13 +19,7 @@ type Action struct {
 }
 
 func (a Action) Binding() key.Binding {
-	prettyKey := strings.ReplaceAll(a.Shortcut, "ctrl", "⌃")
-	prettyKey = strings.ReplaceAll(prettyKey, "alt", "⌥")
-	prettyKey = strings.ReplaceAll(prettyKey, "shift", "⇧")
-	prettyKey = strings.ReplaceAll(prettyKey, "cmd", "⌘")
-	prettyKey = strings.ReplaceAll(prettyKey, "enter", "↩")
-
-	return key.NewBinding(key.WithKeys(a.Shortcut), key.WithHelp(prettyKey, a.Title))
+	return key.NewBinding(key.WithKeys(a.Shortcut), key.WithHelp(prettyKey(a.Shortcut), a.Title))
 }
 
 func NewCopyTextCmd(text string) tea.Cmd {
@@ -37,18 +32,27 @@ func NewCopyTextCmd(text string) tea.Cmd {
 
 type CopyTextMsg struct {
 	Text string
+	// CloseOnAction overrides the closeOnAction setting of the config
+	CloseOnAction *bool
+	// Reload reloads the current page when the program is kept open
+	Reload bool
 }
 
 func NewOpenUrlCmd(Url string) tea.Cmd {
 	return func() tea.Msg {
-		return OpenUrlMsg{
-			Url: Url,
+		return OpenMsg{
+			Target: Url,
 		}
 	}
 }
 
-type OpenUrlMsg struct {
-	Url string
+// OpenMsg opens an url or a local path, using the first matching opener of the config.
+type OpenMsg struct {
+	Target string
+	// Application names the opener or the application to use, instead of matching the target
+	Application   string
+	CloseOnAction *bool
+	Reload        bool
 }
 
 func NewReloadPageCmd(with map[string]app.ScriptInputWithValue) tea.Cmd {
@@ -78,17 +82,8 @@ type RunScriptMsg struct {
 	Script    string
 	With      map[string]app.ScriptInputWithValue
 	OnSuccess string
-}
-
-func (msg RunScriptMsg) OnSuccessCmd() tea.Cmd {
-	switch msg.OnSuccess {
-	case "exit":
-		return tea.Quit
-	case "reload-page":
-		return tea.Sequence(PopCmd, NewReloadPageCmd(nil))
-	default:
-		return tea.Quit
-	}
+	// Confirmed skips the confirmation of the script, when the user already confirmed the action
+	Confirmed bool
 }
 
 func NewExecCmd(command string) tea.Cmd {
@@ -100,42 +95,133 @@ func NewExecCmd(command string) tea.Cmd {
 }
 
 type ExecCommandMsg struct {
+	Title     string
 	Exec      string
 	Directory string
 	OnSuccess string
 	Env       []string
+	// Interactive commands are given the terminal, the program is suspended until they exit
+	Interactive bool
+	// Preferences is the json document sent on a file descriptor, if the extension requests it
+	Preferences []byte
+}
+
+// processExitMsg is sent when an interactive command exits and the program resumes.
+type processExitMsg struct {
+	result tea.Msg
+}
+
+// openerExitMsg is sent once a terminal opener exits.
+type openerExitMsg struct {
+	closeOnAction *bool
+	reload        bool
+}
+
+// ShowOutputMsg displays the output of a command in a detail page.
+type ShowOutputMsg struct {
+	Title   string
+	Output  string
+	Replace bool
 }
 
 func (msg ExecCommandMsg) OnSuccessMsg(output string) tea.Msg {
 	switch msg.OnSuccess {
+	case "exit":
+		return tea.Quit()
 	case "reload-page":
 		return ReloadPageMsg{}
+	case "show-output", "replace-page":
+		return ShowOutputMsg{
+			Title:   msg.Title,
+			Output:  output,
+			Replace: msg.OnSuccess == "replace-page",
+		}
+	case "toast":
+		if strings.TrimSpace(output) == "" {
+			output = "Command succeeded"
+		}
+		return ToastMsg{
+			Level: ToastSuccess,
+			Text:  output,
+		}
 	case "copy-text":
 		return CopyTextMsg{
 			Text: output,
 		}
-	case "open-url":
-		return OpenUrlMsg{
-			Url: strings.TrimSpace(output),
+	case "open-url", "open-path":
+		return OpenMsg{
+			Target: strings.TrimSpace(output),
 		}
-	case "open-path":
-		return OpenUrlMsg{
-			Url: fmt.Sprintf("file://%s", strings.TrimSpace(output)),
+	default:
+		return ToastMsg{
+			Level: ToastError,
+			Text:  fmt.Sprintf("unknown on-success action: %s", msg.OnSuccess),
+		}
+	}
+}
+
+// OnExitMsg reports the exit code of an interactive command, its output was written to the terminal.
+func (msg ExecCommandMsg) OnExitMsg(err error) tea.Msg {
+	if err != nil {
+		return ToastMsg{
+			Level: ToastError,
+			Text:  toastError(err),
+		}
+	}
+
+	toast := ToastMsg{
+		Level: ToastSuccess,
+		Text:  "command exited with code 0",
+	}
+	if msg.OnSuccess == "reload-page" {
+		return withToasts(ReloadPageMsg{}, []ToastMsg{toast})
+	}
+	return toast
+}
+
+// OnErrorMsg reports a command failure, with its exit code and stderr, in the same place as its output.
+func (msg ExecCommandMsg) OnErrorMsg(err error) tea.Msg {
+	switch msg.OnSuccess {
+	case "show-output", "replace-page":
+		return ShowOutputMsg{
+			Title:   msg.Title,
+			Output:  styles.Error.Render(commandError(err).Error()),
+			Replace: msg.OnSuccess == "replace-page",
+		}
+	case "toast":
+		return ToastMsg{
+			Level: ToastError,
+			Text:  toastError(err),
 		}
 	default:
-		return fmt.Errorf("unknown on-success action: %s", msg.OnSuccess)
+		return ToastMsg{
+			Level: ToastError,
+			Text:  toastError(err),
+		}
 	}
 }
 
-func NewEditCmd(path string) tea.Cmd {
-	editor := os.Getenv("EDITOR")
+// NewEditCmd opens the path in the user editor, the page is optionally reloaded once the editor exits.
+func NewEditCmd(path string, reload bool) tea.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
 	if editor == "" {
 		editor = "vi"
 	}
 
+	var onSuccess string
+	if reload {
+		onSuccess = "reload-page"
+	}
+
 	return func() tea.Msg {
+		// The editor command can contain arguments, so only the path is quoted
 		return ExecCommandMsg{
-			Exec: fmt.Sprintf("%s %s", editor, path),
+			Exec:        fmt.Sprintf("%s %s", editor, shellescape.Quote(path)),
+			Interactive: true,
+			OnSuccess:   onSuccess,
 		}
 	}
 }
@@ -147,7 +233,13 @@ func NewAction(scriptAction app.ScriptAction) Action {
 		if scriptAction.Title == "" {
 			scriptAction.Title = "Copy to Clipboard"
 		}
-		cmd = NewCopyTextCmd(scriptAction.Text)
+		cmd = func() tea.Msg {
+			return CopyTextMsg{
+				Text:          scriptAction.Text,
+				CloseOnAction: scriptAction.CloseOnAction,
+				Reload:        scriptAction.OnSuccess == "reload-page",
+			}
+		}
 	case "reload-page":
 		if scriptAction.Title == "" {
 			scriptAction.Title = "Reload Script"
@@ -160,26 +252,55 @@ func NewAction(scriptAction app.ScriptAction) Action {
 				Script:    scriptAction.Script,
 				With:      scriptAction.With,
 				OnSuccess: scriptAction.OnSuccess,
+				Confirmed: scriptAction.Confirm.Required,
 			}
 		}
 	case "open-path":
 		if scriptAction.Title == "" {
 			scriptAction.Title = "Open"
 		}
-		cmd = NewOpenUrlCmd(fmt.Sprintf("file://%s", scriptAction.Path))
+		cmd = func() tea.Msg {
+			return OpenMsg{
+				Target:        scriptAction.Path,
+				CloseOnAction: scriptAction.CloseOnAction,
+				Reload:        scriptAction.OnSuccess == "reload-page",
+			}
+		}
 	case "open-url":
 		if scriptAction.Title == "" {
 			scriptAction.Title = "Open in Browser"
 		}
-		cmd = NewOpenUrlCmd(scriptAction.Url)
+		cmd = func() tea.Msg {
+			return OpenMsg{
+				Target:        scriptAction.Url,
+				CloseOnAction: scriptAction.CloseOnAction,
+				Reload:        scriptAction.OnSuccess == "reload-page",
+			}
+		}
+	case "open-with":
+		if scriptAction.Title == "" {
+			scriptAction.Title = fmt.Sprintf("Open with %s", scriptAction.Application)
+		}
+		cmd = func() tea.Msg {
+			return OpenMsg{
+				Target:        scriptAction.Target,
+				Application:   scriptAction.Application,
+				CloseOnAction: scriptAction.CloseOnAction,
+				Reload:        scriptAction.OnSuccess == "reload-page",
+			}
+		}
 	case "edit":
 		if scriptAction.Title == "" {
 			scriptAction.Title = "Edit File"
 		}
-		cmd = NewEditCmd(scriptAction.Path)
+		cmd = NewEditCmd(scriptAction.Path, scriptAction.OnSuccess == "reload-page")
 	default:
 		scriptAction.Title = "Unknown"
-		cmd = NewErrorCmd(fmt.Errorf("unknown action type: %s", scriptAction.Type))
+		cmd = NewErrorToastCmd(fmt.Errorf("unknown action type: %s", scriptAction.Type))
+	}
+
+	if scriptAction.Confirm.Required {
+		cmd = NewConfirmCmd(scriptAction.Confirm.Message, cmd)
 	}
 
 	return Action{
@@ -204,8 +325,8 @@ func NewActionList() ActionList {
 	header := NewHeader()
 	footer := NewFooter("Actions")
 	footer.SetBindings(
-		key.NewBinding(key.WithKeys("tab"), key.WithHelp("↩", "Confirm")),
-		key.NewBinding(key.WithKeys("tab"), key.WithHelp("⎋", "Hide Actions")),
+		key.NewBinding(key.WithKeys(keymap.Confirm.Keys()...), key.WithHelp(keymap.Confirm.Help().Key, "Confirm")),
+		key.NewBinding(key.WithKeys(keymap.Back.Keys()...), key.WithHelp(keymap.Back.Help().Key, "Hide Actions")),
 	)
 
 	return ActionList{
@@ -231,9 +352,14 @@ func (al *ActionList) SetActions(actions ...Action) {
 	al.actions = actions
 	filterItems := make([]FilterItem, len(actions))
 	for i, action := range actions {
+		shortcut := action.Binding().Help().Key
+		if i == 0 {
+			shortcut = keymap.Confirm.Help().Key
+		}
+
 		filterItems[i] = ListItem{
 			Title:    action.Title,
-			Subtitle: action.Shortcut,
+			Subtitle: shortcut,
 			Actions:  []Action{action},
 		}
 	}
@@ -244,18 +370,14 @@ func (al *ActionList) SetActions(actions ...Action) {
 func (al ActionList) Update(msg tea.Msg) (ActionList, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "tab", "shift+tab":
-			if !al.Focused() {
-				return al, al.Focus()
-			} else if al.Focused() {
-				if msg.String() == "tab" {
-					al.filter.CursorDown()
-				} else {
-					al.filter.CursorUp()
-				}
-			}
-		case "esc":
+		switch {
+		case !al.Focused() && key.Matches(msg, keymap.ShowActions, keymap.FocusPrevious):
+			return al, al.Focus()
+		case al.Focused() && key.Matches(msg, keymap.FocusNext):
+			al.filter.CursorDown()
+		case al.Focused() && key.Matches(msg, keymap.FocusPrevious):
+			al.filter.CursorUp()
+		case key.Matches(msg, keymap.Back):
 			if !al.Focused() {
 				return al, nil
 			}
@@ -269,7 +391,14 @@ func (al ActionList) Update(msg tea.Msg) (ActionList, tea.Cmd) {
 
 			return al, nil
 
-		case "enter":
+		case key.Matches(msg, keymap.Confirm):
+			if !al.Focused() {
+				if len(al.actions) == 0 {
+					return al, nil
+				}
+				return al, al.actions[0].Cmd
+			}
+
 			selectedItem := al.filter.Selection()
 			if selectedItem == nil {
 				return al, nil
@@ -281,6 +410,9 @@ func (al ActionList) Update(msg tea.Msg) (ActionList, tea.Cmd) {
 		}
 
 		for _, action := range al.actions {
+			if action.Shortcut == "" {
+				continue
+			}
 			if key.Matches(msg, action.Binding()) {
 				al.Clear()
 				al.Blur()
@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sunbeamlauncher/sunbeam/utils"
+)
+
+// Confirm is a modal page asking the user to confirm an action before running it.
+type Confirm struct {
+	width, height int
+
+	message string
+	cmd     tea.Cmd
+
+	header Header
+	footer Footer
+	yes    key.Binding
+	no     key.Binding
+}
+
+func NewConfirm(message string, cmd tea.Cmd) *Confirm {
+	if message == "" {
+		message = "Are you sure?"
+	}
+
+	yes := key.NewBinding(key.WithKeys(append([]string{"y"}, keymap.Confirm.Keys()...)...), key.WithHelp(keymap.Confirm.Help().Key, "Yes"))
+	no := key.NewBinding(key.WithKeys(append([]string{"n"}, keymap.Back.Keys()...)...), key.WithHelp(keymap.Back.Help().Key, "No"))
+
+	footer := NewFooter("Confirm")
+	footer.SetBindings(yes, no)
+
+	return &Confirm{
+		message: message,
+		cmd:     cmd,
+		header:  NewHeader(),
+		footer:  footer,
+		yes:     yes,
+		no:      no,
+	}
+}
+
+func NewConfirmCmd(message string, cmd tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		return ConfirmMsg{
+			Message: message,
+			Cmd:     cmd,
+		}
+	}
+}
+
+type ConfirmMsg struct {
+	Message string
+	Cmd     tea.Cmd
+}
+
+type cancelConfirmMsg struct{}
+
+func (c *Confirm) Init() tea.Cmd {
+	return nil
+}
+
+func (c *Confirm) Update(msg tea.Msg) (Page, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, c.yes):
+			return c, tea.Sequence(PopCmd, c.cmd)
+		case key.Matches(msg, c.no):
+			return c, func() tea.Msg {
+				return cancelConfirmMsg{}
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Confirm) SetSize(width, height int) {
+	c.width, c.height = width, height
+	c.header.Width = width
+	c.footer.Width = width
+}
+
+func (c *Confirm) View() string {
+	availableHeight := utils.Max(0, c.height-lipgloss.Height(c.header.View())-lipgloss.Height(c.footer.View()))
+
+	message := lipgloss.JoinVertical(
+		lipgloss.Center,
+		styles.Bold.Render(c.message),
+		"",
+		styles.Faint.Render("[y]es / [n]o"),
+	)
+	message = lipgloss.Place(c.width, availableHeight, lipgloss.Center, lipgloss.Center, message)
+
+	return lipgloss.JoinVertical(lipgloss.Left, c.header.View(), message, c.footer.View())
+}
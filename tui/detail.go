@@ -2,26 +2,31 @@ package tui
 
 import (
 	"fmt"
+	"image"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/muesli/reflow/wordwrap"
 	"github.com/sunbeamlauncher/sunbeam/app"
 	"github.com/sunbeamlauncher/sunbeam/utils"
 )
 
 type Detail struct {
-	header       Header
-	Style        lipgloss.Style
-	content      string
-	metadatas    []app.ScriptMetadata
-	mainViewport viewport.Model
-	sideViewport viewport.Model
-	actionList   ActionList
-	footer       Footer
+	header        Header
+	Style         lipgloss.Style
+	content       string
+	format        string
+	image         image.Image
+	imageErr      error
+	terminalImage TerminalImage
+	metadatas     []app.ScriptMetadata
+	mainViewport  viewport.Model
+	sideViewport  viewport.Model
+	actionList    ActionList
+	footer        Footer
 }
 
 func NewDetail(title string) *Detail {
@@ -53,8 +58,8 @@ func (c *Detail) SetActions(actions ...Action) {
 		c.footer.SetBindings()
 	} else {
 		c.footer.SetBindings(
-			key.NewBinding(key.WithKeys("enter"), key.WithHelp("↩", actions[0].Title)),
-			key.NewBinding(key.WithKeys("tab"), key.WithHelp("⇥", "Show Actions")),
+			key.NewBinding(key.WithKeys(keymap.Confirm.Keys()...), key.WithHelp(keymap.Confirm.Help().Key, actions[0].Title)),
+			keymap.ShowActions,
 		)
 	}
 }
@@ -64,18 +69,20 @@ func (d *Detail) Init() tea.Cmd {
 }
 
 func (d *Detail) updateContent() {
-	mainContent := wordwrap.String(d.content, utils.Max(0, d.mainViewport.Width-2))
+	mainContent, err := renderContent(d.content, d.format, utils.Max(0, d.mainViewport.Width-2))
+	if err != nil {
+		mainContent = wrapContent(d.content, utils.Max(0, d.mainViewport.Width-2))
+	}
+	if imageBlock := d.renderImage(); imageBlock != "" {
+		mainContent = lipgloss.JoinVertical(lipgloss.Left, imageBlock, "", mainContent)
+	}
 	mainContent = lipgloss.NewStyle().Padding(0, 1).Width(d.mainViewport.Width).Render(mainContent)
 	d.mainViewport.SetContent(mainContent)
 
 	items := make([]string, 0)
 	maxWidth := utils.Max(d.sideViewport.Width-2, 0)
 	for _, metadata := range d.metadatas {
-		items = append(items, fmt.Sprintf(
-			"%s\n%s",
-			styles.Faint.MaxWidth(maxWidth).Render(metadata.Title),
-			lipgloss.NewStyle().MaxWidth(maxWidth).Render(metadata.Value),
-		))
+		items = append(items, renderMetadata(metadata, maxWidth))
 	}
 
 	sideContent := strings.Join(items, "\n\n")
@@ -83,6 +90,112 @@ func (d *Detail) updateContent() {
 	d.sideViewport.SetContent(sideContent)
 }
 
+func renderMetadata(metadata app.ScriptMetadata, maxWidth int) string {
+	var value string
+	switch metadata.Type {
+	case "separator":
+		return styles.Border.Render(strings.Repeat("─", maxWidth))
+	case "link":
+		value = styles.Accent.Copy().Underline(true).MaxWidth(maxWidth).Render(metadata.Value)
+	case "date":
+		if date, err := utils.ParseDate(metadata.Value); err == nil {
+			value = utils.RelativeTime(date, time.Now())
+		} else {
+			value = metadata.Value
+		}
+		value = lipgloss.NewStyle().MaxWidth(maxWidth).Render(value)
+	case "tags":
+		value = renderTags(metadata.Tags, maxWidth)
+	default:
+		value = lipgloss.NewStyle().MaxWidth(maxWidth).Render(metadata.Value)
+	}
+
+	return fmt.Sprintf("%s\n%s", styles.Faint.MaxWidth(maxWidth).Render(metadata.Title), value)
+}
+
+var tagColors = map[string]string{
+	"black":   "0",
+	"red":     "1",
+	"green":   "2",
+	"yellow":  "3",
+	"blue":    "4",
+	"magenta": "5",
+	"cyan":    "6",
+	"white":   "7",
+	"gray":    "8",
+}
+
+// renderTags renders the tags as colored pills, wrapping them to fit the sidebar.
+func renderTags(tags []app.MetadataTag, maxWidth int) string {
+	lines := make([]string, 0)
+	line := ""
+	for _, tag := range tags {
+		color, ok := tagColors[tag.Color]
+		if !ok {
+			color = tag.Color
+		}
+		if color == "" {
+			color = tagColors["gray"]
+		}
+
+		pill := styles.Tag.Copy().
+			Background(lipgloss.Color(color)).
+			Padding(0, 1).
+			MaxWidth(maxWidth).
+			Render(tag.Text)
+
+		if line == "" {
+			line = pill
+		} else if lipgloss.Width(line)+1+lipgloss.Width(pill) <= maxWidth {
+			line = fmt.Sprintf("%s %s", line, pill)
+		} else {
+			lines = append(lines, line)
+			line = pill
+		}
+	}
+
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// renderImage returns the half-block rendering of the image,
+// or the blank area on top of which the graphics overlay is drawn.
+func (d *Detail) renderImage() string {
+	d.terminalImage = TerminalImage{}
+	if d.imageErr != nil {
+		return styles.Error.Render(fmt.Sprintf("failed to load image: %s", d.imageErr))
+	}
+	if d.image == nil {
+		return ""
+	}
+
+	terminalImage, err := renderImage(d.image, imageProtocol, utils.Max(0, d.mainViewport.Width-2), d.mainViewport.Height)
+	if err != nil {
+		return styles.Error.Render(fmt.Sprintf("failed to render image: %s", err))
+	}
+	d.terminalImage = terminalImage
+
+	if terminalImage.Escape == "" {
+		return terminalImage.Blocks
+	}
+	return strings.Repeat("\n", utils.Max(0, terminalImage.Rows-1))
+}
+
+func (d *Detail) Overlay() (ImageOverlay, bool) {
+	if d.terminalImage.Escape == "" || d.actionList.Focused() || d.mainViewport.YOffset > 0 {
+		return ImageOverlay{}, false
+	}
+
+	return ImageOverlay{
+		Row:    lipgloss.Height(d.header.View()),
+		Col:    1,
+		Escape: d.terminalImage.Escape,
+	}, true
+}
+
 type DetailMsg string
 
 func (d *Detail) SetContent(content string) {
@@ -96,8 +209,23 @@ func (d *Detail) SetDetail(detail app.Detail) tea.Cmd {
 		actions[i] = NewAction(action)
 	}
 
+	for _, metadata := range detail.Metadatas {
+		if metadata.Type != "link" {
+			continue
+		}
+		actions = append(actions, Action{
+			Title: fmt.Sprintf("Open %s", metadata.Title),
+			Cmd:   NewOpenUrlCmd(metadata.Value),
+		})
+	}
+
 	d.SetActions(actions...)
 	d.content = detail.Preview
+	d.format = detail.Format
+	d.image, d.imageErr = nil, nil
+	if detail.Image != "" {
+		d.image, d.imageErr = loadImage(detail.Image)
+	}
 	d.metadatas = detail.Metadatas
 	d.updateContent()
 
@@ -111,21 +239,18 @@ func (d *Detail) SetIsLoading(isLoading bool) tea.Cmd {
 func (c Detail) Update(msg tea.Msg) (Page, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyRunes:
-			switch msg.String() {
-			case "q", "Q":
-				return &c, tea.Quit
-			}
-		case tea.KeyEscape:
+		switch {
+		case key.Matches(msg, keymap.QuitDetail) && !c.actionList.Focused():
+			return &c, tea.Quit
+		case key.Matches(msg, keymap.Back):
 			if c.actionList.Focused() {
 				break
 			}
 			return &c, PopCmd
-		case tea.KeyShiftDown:
+		case key.Matches(msg, keymap.ScrollDown):
 			c.sideViewport.LineDown(1)
 			return &c, nil
-		case tea.KeyShiftUp:
+		case key.Matches(msg, keymap.ScrollUp):
 			c.sideViewport.LineUp(1)
 			return &c, nil
 		}
@@ -181,7 +306,7 @@ func (c *Detail) View() string {
 		for i := 0; i < c.mainViewport.Height; i++ {
 			separatorChars[i] = "│"
 		}
-		separator := strings.Join(separatorChars, "\n")
+		separator := styles.Border.Render(strings.Join(separatorChars, "\n"))
 
 		view := lipgloss.JoinHorizontal(lipgloss.Top,
 			c.mainViewport.View(),
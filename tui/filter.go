@@ -4,6 +4,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/sahilm/fuzzy"
@@ -99,7 +100,7 @@ func (m Filter) View() string {
 
 		if availableHeight > 0 && m.DrawLines {
 			separator := strings.Repeat("─", itemWidth)
-			separator = styles.Faint.Render(separator)
+			separator = styles.Border.Render(separator)
 			rows = append(rows, separator)
 			availableHeight--
 		}
@@ -124,10 +125,10 @@ func (m Filter) View() string {
 func (f Filter) Update(msg tea.Msg) (Filter, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+j", "down":
+		switch {
+		case key.Matches(msg, keymap.CursorDown):
 			f.CursorDown()
-		case "ctrl+k", "up":
+		case key.Matches(msg, keymap.CursorUp):
 			f.CursorUp()
 		}
 	}
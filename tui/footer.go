@@ -26,7 +26,7 @@ func (f *Footer) SetBindings(bindings ...key.Binding) {
 }
 
 func (f Footer) View() string {
-	horizontal := strings.Repeat("─", f.Width)
+	horizontal := styles.Border.Render(strings.Repeat("─", f.Width))
 
 	if len(f.bindings) == 0 {
 		title := styles.Italic.Copy().Padding(0, 1).Width(f.Width).Render(f.title)
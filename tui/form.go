@@ -320,7 +320,7 @@ func (dd DropDown) View() string {
 	if !dd.textinput.Focused() || dd.HasMatch() {
 		return textInputView
 	} else {
-		separator := strings.Repeat("─", dd.filter.Width)
+		separator := styles.Border.Render(strings.Repeat("─", dd.filter.Width))
 		return lipgloss.JoinVertical(lipgloss.Left, textInputView, separator, dd.filter.View())
 	}
 }
@@ -400,8 +400,8 @@ func NewForm(name string, title string, items []FormItem) *Form {
 	viewport := viewport.New(0, 0)
 	footer := NewFooter(title)
 	footer.SetBindings(
-		key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("⌃S", "Submit")),
-		key.NewBinding(key.WithKeys("tab"), key.WithHelp("⇥", "Focus Next")),
+		keymap.Submit,
+		keymap.FocusNext,
 	)
 
 	return &Form{
@@ -453,15 +453,13 @@ func (c Form) Update(msg tea.Msg) (Page, tea.Cmd) {
 	// Handle character input and blinking
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyEscape:
+		switch {
+		case key.Matches(msg, keymap.Back):
 			return &c, PopCmd
 		// Set focus to next input
-		case tea.KeyTab, tea.KeyShiftTab:
-			s := msg.String()
-
+		case key.Matches(msg, keymap.FocusNext, keymap.FocusPrevious):
 			// Cycle indexes
-			if s == "up" || s == "shift+tab" {
+			if key.Matches(msg, keymap.FocusPrevious) {
 				c.focusIndex--
 			} else {
 				c.focusIndex++
@@ -488,7 +486,7 @@ func (c Form) Update(msg tea.Msg) (Page, tea.Cmd) {
 			c.ScrollViewport()
 
 			return &c, tea.Batch(cmds...)
-		case tea.KeyCtrlS:
+		case key.Matches(msg, keymap.Submit):
 			values := make(map[string]any)
 			for _, input := range c.items {
 				values[input.Id] = input.Value()
@@ -528,8 +526,8 @@ func (c *Form) SetSize(width, height int) {
 }
 
 func (c *Form) View() string {
-	selectedBorder := lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).BorderForeground(lipgloss.Color("13"))
-	normalBorder := lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true)
+	selectedBorder := lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).BorderForeground(styles.Selection.GetForeground())
+	normalBorder := lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).BorderForeground(styles.Border.GetForeground())
 	itemViews := make([]string, len(c.items))
 	maxWidth := 0
 	for i, item := range c.items {
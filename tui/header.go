@@ -22,7 +22,7 @@ func NewHeader() Header {
 	ti.Placeholder = ""
 	ti.PlaceholderStyle = styles.Faint.Copy()
 	spinner := spinner.New()
-	spinner.Style = lipgloss.NewStyle().Padding(0, 1)
+	spinner.Style = styles.Accent.Copy().Padding(0, 1)
 	return Header{
 		input:   ti,
 		spinner: spinner,
@@ -87,6 +87,6 @@ func (c Header) View() string {
 	}
 
 	line := strings.Repeat("─", c.Width)
-	line = styles.Bold.Render(line)
+	line = styles.Border.Copy().Bold(true).Render(line)
 	return lipgloss.JoinVertical(lipgloss.Left, headerRow, line)
 }
@@ -0,0 +1,448 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+const (
+	ImageProtocolKitty  = "kitty"
+	ImageProtocolITerm  = "iterm"
+	ImageProtocolSixel  = "sixel"
+	ImageProtocolBlocks = "blocks"
+)
+
+// imageProtocol is the protocol used to draw images, it is set by Draw.
+// Graphics protocols need absolute positioning, so they are only enabled in fullscreen mode.
+var imageProtocol = ImageProtocolBlocks
+
+func initImageProtocol(protocol string, fullscreen bool) {
+	if protocol == "" || protocol == "auto" {
+		protocol = detectImageProtocol()
+	}
+
+	if !fullscreen {
+		protocol = ImageProtocolBlocks
+	}
+
+	imageProtocol = protocol
+}
+
+func detectImageProtocol() string {
+	// Multiplexers swallow graphics escape sequences
+	if os.Getenv("TMUX") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return ImageProtocolBlocks
+	}
+
+	if os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty" {
+		return ImageProtocolKitty
+	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm":
+		return ImageProtocolITerm
+	case "mlterm":
+		return ImageProtocolSixel
+	}
+
+	term := os.Getenv("TERM")
+	if strings.HasPrefix(term, "foot") || strings.Contains(term, "sixel") || strings.HasPrefix(term, "mlterm") {
+		return ImageProtocolSixel
+	}
+
+	return ImageProtocolBlocks
+}
+
+// loadImage decodes an image from a local path or a data URI.
+func loadImage(ref string) (image.Image, error) {
+	var data []byte
+	if strings.HasPrefix(ref, "data:") {
+		header, payload, ok := strings.Cut(strings.TrimPrefix(ref, "data:"), ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, errors.New("only base64 encoded data URIs are supported")
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data URI: %w", err)
+		}
+		data = decoded
+	} else {
+		content, err := os.ReadFile(ref)
+		if err != nil {
+			return nil, err
+		}
+		data = content
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return img, nil
+}
+
+// cellSize returns the size of a terminal cell in pixels.
+func cellSize() (width int, height int) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Xpixel == 0 || ws.Ypixel == 0 || ws.Col == 0 || ws.Row == 0 {
+		return 10, 20
+	}
+
+	return int(ws.Xpixel / ws.Col), int(ws.Ypixel / ws.Row)
+}
+
+// TerminalImage is an image sized to fit a block of cells.
+type TerminalImage struct {
+	Cols, Rows int
+	// Escape is the graphics escape sequence drawing the image, it is empty when the image is rendered as text.
+	Escape string
+	// Blocks is the half-block rendering of the image.
+	Blocks string
+}
+
+func renderImage(img image.Image, protocol string, maxCols, maxRows int) (TerminalImage, error) {
+	if maxCols <= 0 || maxRows <= 0 {
+		return TerminalImage{}, nil
+	}
+
+	bounds := img.Bounds()
+	if protocol == ImageProtocolBlocks {
+		// Each cell holds two vertical pixels
+		width, height := fitSize(bounds.Dx(), bounds.Dy(), maxCols, maxRows*2, true)
+		return TerminalImage{
+			Cols:   width,
+			Rows:   (height + 1) / 2,
+			Blocks: encodeBlocks(resizeImage(img, width, height)),
+		}, nil
+	}
+
+	cellWidth, cellHeight := cellSize()
+	width, height := fitSize(bounds.Dx(), bounds.Dy(), maxCols*cellWidth, maxRows*cellHeight, false)
+	cols := ceilDiv(width, cellWidth)
+	rows := ceilDiv(height, cellHeight)
+
+	var escape string
+	var err error
+	switch protocol {
+	case ImageProtocolKitty:
+		escape, err = encodeKitty(img, cols, rows)
+	case ImageProtocolITerm:
+		escape, err = encodeITerm(img, cols, rows)
+	case ImageProtocolSixel:
+		escape = encodeSixel(resizeImage(img, width, height))
+	default:
+		return TerminalImage{}, fmt.Errorf("unknown image protocol: %s", protocol)
+	}
+	if err != nil {
+		return TerminalImage{}, err
+	}
+
+	return TerminalImage{Cols: cols, Rows: rows, Escape: escape}, nil
+}
+
+// fitSize scales width and height to fit in the given box, preserving the aspect ratio.
+func fitSize(width, height, maxWidth, maxHeight int, upscale bool) (int, int) {
+	if width == 0 || height == 0 {
+		return 0, 0
+	}
+
+	scale := math.Min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+	if !upscale {
+		scale = math.Min(scale, 1)
+	}
+
+	return int(math.Max(1, math.Floor(float64(width)*scale))), int(math.Max(1, math.Floor(float64(height)*scale)))
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
+
+func resizeImage(img image.Image, width, height int) image.Image {
+	bounds := img.Bounds()
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		sy := bounds.Min.Y + y*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			sx := bounds.Min.X + x*bounds.Dx()/width
+			resized.Set(x, y, img.At(sx, sy))
+		}
+	}
+
+	return resized
+}
+
+func hexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+// encodeBlocks renders the image using the upper half block character,
+// the foreground color paints the top pixel and the background color the bottom one.
+func encodeBlocks(img image.Image) string {
+	profile := lipgloss.ColorProfile()
+	bounds := img.Bounds()
+
+	rows := make([]string, 0, (bounds.Dy()+1)/2)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 2 {
+		var row strings.Builder
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			block := termenv.String("▀").Foreground(profile.Color(hexColor(img.At(x, y))))
+			if y+1 < bounds.Max.Y {
+				block = block.Background(profile.Color(hexColor(img.At(x, y+1))))
+			}
+			row.WriteString(block.String())
+		}
+		rows = append(rows, row.String())
+	}
+
+	return strings.Join(rows, "\n")
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeKitty implements the kitty graphics protocol: https://sw.kovidgoyal.net/kitty/graphics-protocol
+func encodeKitty(img image.Image, cols, rows int) (string, error) {
+	data, err := encodePNG(img)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.StdEncoding.EncodeToString(data)
+	chunkSize := 4096
+
+	var builder strings.Builder
+	for i := 0; i < len(payload); i += chunkSize {
+		end := i + chunkSize
+		more := 1
+		if end >= len(payload) {
+			end = len(payload)
+			more = 0
+		}
+
+		if i == 0 {
+			fmt.Fprintf(&builder, "\x1b_Ga=T,f=100,q=2,C=1,c=%d,r=%d,m=%d;%s\x1b\\", cols, rows, more, payload[i:end])
+		} else {
+			fmt.Fprintf(&builder, "\x1b_Gm=%d;%s\x1b\\", more, payload[i:end])
+		}
+	}
+
+	return builder.String(), nil
+}
+
+// encodeITerm implements the iTerm2 inline images protocol: https://iterm2.com/documentation-images.html
+func encodeITerm(img image.Image, cols, rows int) (string, error) {
+	data, err := encodePNG(img)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(
+		"\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+		len(data),
+		cols,
+		rows,
+		base64.StdEncoding.EncodeToString(data),
+	), nil
+}
+
+// encodeSixel quantizes the image to a 256 colors palette and encodes it as a sixel sequence.
+func encodeSixel(img image.Image) string {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	paletted := image.NewPaletted(image.Rect(0, 0, width, height), palette.Plan9)
+	draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), img, bounds.Min)
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "\x1bPq\"1;1;%d;%d", width, height)
+
+	used := make([]bool, len(paletted.Palette))
+	for _, index := range paletted.Pix {
+		used[index] = true
+	}
+	for index, c := range paletted.Palette {
+		if !used[index] {
+			continue
+		}
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(&builder, "#%d;2;%d;%d;%d", index, r*100/0xffff, g*100/0xffff, b*100/0xffff)
+	}
+
+	for y0 := 0; y0 < height; y0 += 6 {
+		bandColors := make([]bool, len(paletted.Palette))
+		for y := y0; y < y0+6 && y < height; y++ {
+			for x := 0; x < width; x++ {
+				bandColors[paletted.ColorIndexAt(x, y)] = true
+			}
+		}
+
+		for index, inBand := range bandColors {
+			if !inBand {
+				continue
+			}
+
+			fmt.Fprintf(&builder, "#%d", index)
+			var last byte
+			count := 0
+			flush := func() {
+				if count == 0 {
+					return
+				}
+				if count > 3 {
+					fmt.Fprintf(&builder, "!%d%c", count, last)
+				} else {
+					builder.WriteString(strings.Repeat(string(last), count))
+				}
+			}
+
+			for x := 0; x < width; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && y0+dy < height; dy++ {
+					if int(paletted.ColorIndexAt(x, y0+dy)) == index {
+						bits |= 1 << dy
+					}
+				}
+
+				char := 63 + bits
+				if char == last {
+					count++
+					continue
+				}
+				flush()
+				last, count = char, 1
+			}
+			flush()
+			builder.WriteByte('$')
+		}
+		builder.WriteByte('-')
+	}
+
+	builder.WriteString("\x1b\\")
+	return builder.String()
+}
+
+// ImageOverlay is a graphics escape sequence drawn at an absolute position,
+// on top of the area reserved by the page.
+type ImageOverlay struct {
+	Row, Col int
+	Escape   string
+}
+
+// OverlayPage is implemented by pages displaying images through a graphics protocol.
+type OverlayPage interface {
+	Overlay() (ImageOverlay, bool)
+}
+
+type drawOverlayMsg struct {
+	overlay ImageOverlay
+}
+
+// overlayDrawDelay leaves time for the renderer to flush the frame reserving the image area.
+var overlayDrawDelay = 50 * time.Millisecond
+
+// rendererOutput serializes the writes of the renderer and of the image overlays,
+// so that an overlay is never written in the middle of a frame.
+type rendererOutput struct {
+	sync.Mutex
+	*os.File
+}
+
+func (o *rendererOutput) Write(p []byte) (int, error) {
+	o.Lock()
+	defer o.Unlock()
+	return o.File.Write(p)
+}
+
+// overlayOutput is the output of the program when images are drawn with a graphics protocol.
+var overlayOutput = &rendererOutput{File: os.Stdout}
+
+// overlayEscape returns the sequence drawing the overlay, the previous image is cleared first.
+func overlayEscape(protocol string, overlay *ImageOverlay) string {
+	var builder strings.Builder
+	if protocol == ImageProtocolKitty {
+		// Delete all visible placements
+		builder.WriteString("\x1b_Ga=d,q=2\x1b\\")
+	}
+
+	if overlay != nil {
+		fmt.Fprintf(&builder, "\x1b7\x1b[%d;%dH%s\x1b8", overlay.Row+1, overlay.Col+1, overlay.Escape)
+	}
+
+	return builder.String()
+}
+
+// newWriteOverlayCmd draws the overlay, or clears the previous one if it is nil.
+func newWriteOverlayCmd(overlay *ImageOverlay) tea.Cmd {
+	escape := overlayEscape(imageProtocol, overlay)
+	return func() tea.Msg {
+		overlayOutput.Write([]byte(escape))
+		return nil
+	}
+}
+
+func newDrawOverlayCmd(overlay ImageOverlay) tea.Cmd {
+	return tea.Tick(overlayDrawDelay, func(_ time.Time) tea.Msg {
+		return drawOverlayMsg{overlay: overlay}
+	})
+}
+
+// forwardResize sends the size of the terminal to the program,
+// since bubbletea only watches the size of the outputs which are an *os.File.
+func forwardResize(p *tea.Program) (stop func()) {
+	sendSize := func() {
+		if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			p.Send(tea.WindowSizeMsg{Width: width, Height: height})
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		sendSize()
+		for {
+			select {
+			case <-signals:
+				sendSize()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
@@ -0,0 +1,161 @@
+package tui
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// quadrants is a 4x4 image with a red, a green, a blue and a white quadrant.
+func quadrants() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	colors := []color.Color{
+		color.RGBA{255, 0, 0, 255},
+		color.RGBA{0, 255, 0, 255},
+		color.RGBA{0, 0, 255, 255},
+		color.RGBA{255, 255, 255, 255},
+	}
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, colors[(y/2)*2+x/2])
+		}
+	}
+	return img
+}
+
+// noise is an image which does not compress well, so that its kitty payload is split in several chunks.
+func noise() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 48, 48))
+	seed := uint32(1)
+	for i := range img.Pix {
+		seed = seed*1664525 + 1013904223
+		img.Pix[i] = byte(seed >> 24)
+	}
+	return img
+}
+
+func assertGolden(t *testing.T, name string, actual string) {
+	t.Helper()
+	goldenPath := path.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(goldenPath, []byte(actual), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("missing golden file, run go test ./tui -update: %s", err)
+	}
+
+	if actual != string(expected) {
+		t.Errorf("%s does not match %s:\n%q\n%q", name, goldenPath, actual, expected)
+	}
+}
+
+func TestImageEncoders(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	defer lipgloss.SetColorProfile(profile)
+
+	cases := map[string]func() (string, error){
+		"blocks": func() (string, error) {
+			return encodeBlocks(quadrants()), nil
+		},
+		"blocks_odd_height": func() (string, error) {
+			return encodeBlocks(resizeImage(quadrants(), 4, 3)), nil
+		},
+		"kitty": func() (string, error) {
+			return encodeKitty(quadrants(), 2, 1)
+		},
+		"kitty_chunked": func() (string, error) {
+			return encodeKitty(noise(), 5, 3)
+		},
+		"iterm": func() (string, error) {
+			return encodeITerm(quadrants(), 2, 1)
+		},
+		"sixel": func() (string, error) {
+			return encodeSixel(quadrants()), nil
+		},
+		"sixel_bands": func() (string, error) {
+			return encodeSixel(resizeImage(quadrants(), 8, 8)), nil
+		},
+	}
+
+	for name, encode := range cases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, name, actual)
+		})
+	}
+}
+
+func TestOverlayEscape(t *testing.T) {
+	overlay := &ImageOverlay{Row: 2, Col: 10, Escape: "<image>"}
+
+	if actual, expected := overlayEscape(ImageProtocolSixel, overlay), "\x1b7\x1b[3;11H<image>\x1b8"; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	// The kitty placements are deleted before the new image is drawn
+	if actual, expected := overlayEscape(ImageProtocolKitty, overlay), "\x1b_Ga=d,q=2\x1b\\\x1b7\x1b[3;11H<image>\x1b8"; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	if actual := overlayEscape(ImageProtocolITerm, nil); actual != "" {
+		t.Errorf("expected no escape sequence, got %q", actual)
+	}
+}
+
+func TestRenderImageBlocks(t *testing.T) {
+	img, err := renderImage(quadrants(), ImageProtocolBlocks, 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The image is upscaled to fit 4 rows of pixels, 2 per cell
+	if img.Cols != 4 || img.Rows != 2 || img.Escape != "" || img.Blocks == "" {
+		t.Errorf("unexpected image: %+v", img)
+	}
+
+	if img, _ := renderImage(quadrants(), ImageProtocolBlocks, 0, 2); img.Blocks != "" {
+		t.Errorf("expected an empty image, got %+v", img)
+	}
+}
+
+func TestFitSize(t *testing.T) {
+	cases := []struct {
+		width, height, maxWidth, maxHeight int
+		upscale                            bool
+		wantWidth, wantHeight              int
+	}{
+		{100, 50, 50, 50, false, 50, 25},
+		{50, 100, 50, 50, false, 25, 50},
+		{10, 10, 50, 20, false, 10, 10},
+		{10, 10, 50, 20, true, 20, 20},
+		{1000, 1, 10, 10, false, 10, 1},
+		{0, 10, 10, 10, false, 0, 0},
+	}
+
+	for _, tc := range cases {
+		width, height := fitSize(tc.width, tc.height, tc.maxWidth, tc.maxHeight, tc.upscale)
+		if width != tc.wantWidth || height != tc.wantHeight {
+			t.Errorf("fitSize(%d, %d, %d, %d, %v) = %d, %d, expected %d, %d", tc.width, tc.height, tc.maxWidth, tc.maxHeight, tc.upscale, width, height, tc.wantWidth, tc.wantHeight)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+type KeyMap struct {
+	Quit          key.Binding
+	Close         key.Binding
+	Back          key.Binding
+	Confirm       key.Binding
+	CursorUp      key.Binding
+	CursorDown    key.Binding
+	ShowActions   key.Binding
+	FocusNext     key.Binding
+	FocusPrevious key.Binding
+	Submit        key.Binding
+	ScrollUp      key.Binding
+	ScrollDown    key.Binding
+	QuitDetail    key.Binding
+}
+
+var keymap = DefaultKeyMap()
+
+// SetKeyMap replaces the bindings used by all the components.
+// It must be called before the pages are created.
+func SetKeyMap(km KeyMap) {
+	keymap = km
+}
+
+func newBinding(desc string, keys ...string) key.Binding {
+	return key.NewBinding(key.WithKeys(keys...), key.WithHelp(prettyKey(keys[0]), desc))
+}
+
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Quit:          newBinding("Quit", "ctrl+c"),
+		Close:         newBinding("Close", "ctrl+w"),
+		Back:          newBinding("Back", "esc"),
+		Confirm:       newBinding("Confirm", "enter"),
+		CursorUp:      newBinding("Up", "up", "ctrl+k"),
+		CursorDown:    newBinding("Down", "down", "ctrl+j"),
+		ShowActions:   newBinding("Show Actions", "tab"),
+		FocusNext:     newBinding("Focus Next", "tab"),
+		FocusPrevious: newBinding("Focus Previous", "shift+tab"),
+		Submit:        newBinding("Submit", "ctrl+s"),
+		ScrollUp:      newBinding("Scroll Up", "shift+up"),
+		ScrollDown:    newBinding("Scroll Down", "shift+down"),
+		QuitDetail:    newBinding("Quit", "q", "Q"),
+	}
+}
+
+func (km *KeyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"quit":          &km.Quit,
+		"close":         &km.Close,
+		"back":          &km.Back,
+		"confirm":       &km.Confirm,
+		"cursorUp":      &km.CursorUp,
+		"cursorDown":    &km.CursorDown,
+		"showActions":   &km.ShowActions,
+		"focusNext":     &km.FocusNext,
+		"focusPrevious": &km.FocusPrevious,
+		"submit":        &km.Submit,
+		"scrollUp":      &km.ScrollUp,
+		"scrollDown":    &km.ScrollDown,
+		"quitDetail":    &km.QuitDetail,
+	}
+}
+
+// keymapScopes lists the bindings which are active at the same time.
+// The global bindings are checked against every scope.
+// The list and detail scopes include focusPrevious, since it also opens their action list.
+var keymapScopes = map[string][]string{
+	"global":  {"quit", "close"},
+	"list":    {"back", "confirm", "cursorUp", "cursorDown", "showActions", "focusPrevious", "scrollUp", "scrollDown"},
+	"actions": {"back", "confirm", "cursorUp", "cursorDown", "focusNext", "focusPrevious"},
+	"form":    {"back", "submit", "focusNext", "focusPrevious"},
+	"detail":  {"back", "confirm", "showActions", "focusPrevious", "scrollUp", "scrollDown", "quitDetail"},
+}
+
+// NewKeyMap overrides the default bindings with the ones defined in the config.
+// The names are matched case-insensitively, since viper lowercases the config keys.
+func NewKeyMap(overrides map[string][]string) (KeyMap, error) {
+	km := DefaultKeyMap()
+	bindings := km.bindings()
+
+	names := make(map[string]string)
+	for name := range bindings {
+		names[strings.ToLower(name)] = name
+	}
+
+	for override, keys := range overrides {
+		name, ok := names[strings.ToLower(override)]
+		if !ok {
+			return km, fmt.Errorf("unknown binding: %s", override)
+		}
+
+		if len(keys) == 0 {
+			return km, fmt.Errorf("binding %s has no keys", name)
+		}
+
+		binding := bindings[name]
+		*binding = newBinding(binding.Help().Desc, keys...)
+	}
+
+	return km, km.Validate()
+}
+
+// Validate checks that a key is not bound to two actions of the same scope.
+func (km KeyMap) Validate() error {
+	bindings := km.bindings()
+
+	scopes := make([]string, 0, len(keymapScopes))
+	for scope := range keymapScopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	for _, scope := range scopes {
+		names := append([]string{}, keymapScopes[scope]...)
+		if scope != "global" {
+			names = append(names, keymapScopes["global"]...)
+		}
+
+		owners := make(map[string]string)
+		for _, name := range names {
+			for _, k := range bindings[name].Keys() {
+				if owner, ok := owners[k]; ok && owner != name {
+					return fmt.Errorf("key %s is bound to both %s and %s", k, owner, name)
+				}
+				owners[k] = name
+			}
+		}
+	}
+
+	return nil
+}
+
+var keySymbols = map[string]string{
+	"ctrl":      "⌃",
+	"alt":       "⌥",
+	"shift":     "⇧",
+	"cmd":       "⌘",
+	"enter":     "↩",
+	"tab":       "⇥",
+	"esc":       "⎋",
+	"up":        "↑",
+	"down":      "↓",
+	"left":      "←",
+	"right":     "→",
+	"backspace": "⌫",
+}
+
+// prettyKey formats a key for the help views, e.g. ctrl+s becomes ⌃S.
+func prettyKey(k string) string {
+	parts := strings.Split(k, "+")
+	for i, part := range parts {
+		if symbol, ok := keySymbols[part]; ok {
+			parts[i] = symbol
+		} else if len(parts) > 1 {
+			parts[i] = strings.ToUpper(part)
+		}
+	}
+
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,109 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDefaultKeyMap(t *testing.T) {
+	if err := DefaultKeyMap().Validate(); err != nil {
+		t.Fatalf("expected the default keymap to be valid, got %s", err)
+	}
+
+	for _, k := range []string{"q", "Q"} {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		if !key.Matches(msg, DefaultKeyMap().QuitDetail) {
+			t.Errorf("expected %s to quit the detail view", k)
+		}
+	}
+}
+
+func TestNewKeyMap(t *testing.T) {
+	cases := map[string]struct {
+		overrides map[string][]string
+		wantErr   string
+		check     func(KeyMap) bool
+	}{
+		"no overrides": {
+			check: func(km KeyMap) bool {
+				return reflect.DeepEqual(km.Submit.Keys(), []string{"ctrl+s"})
+			},
+		},
+		"override": {
+			overrides: map[string][]string{"submit": {"ctrl+enter", "alt+s"}},
+			check: func(km KeyMap) bool {
+				return reflect.DeepEqual(km.Submit.Keys(), []string{"ctrl+enter", "alt+s"}) && km.Submit.Help().Key == "⌃↩" && km.Submit.Help().Desc == "Submit"
+			},
+		},
+		// viper lowercases the keys of the config
+		"case insensitive": {
+			overrides: map[string][]string{"cursordown": {"ctrl+n"}},
+			check: func(km KeyMap) bool {
+				return reflect.DeepEqual(km.CursorDown.Keys(), []string{"ctrl+n"})
+			},
+		},
+		"unknown binding": {
+			overrides: map[string][]string{"explode": {"x"}},
+			wantErr:   "unknown binding",
+		},
+		"no keys": {
+			overrides: map[string][]string{"quit": {}},
+			wantErr:   "has no keys",
+		},
+		"conflict in a scope": {
+			overrides: map[string][]string{"scrollDown": {"ctrl+j"}},
+			wantErr:   "key ctrl+j is bound to both",
+		},
+		"conflict with a global binding": {
+			overrides: map[string][]string{"submit": {"ctrl+c"}},
+			wantErr:   "key ctrl+c is bound to both",
+		},
+		"conflict with focus previous in a list": {
+			overrides: map[string][]string{"scrollUp": {"shift+tab"}},
+			wantErr:   "key shift+tab is bound to both",
+		},
+		// The form and the list are never active at the same time
+		"same key in different scopes": {
+			overrides: map[string][]string{"submit": {"ctrl+k"}},
+			check: func(km KeyMap) bool {
+				return reflect.DeepEqual(km.Submit.Keys(), []string{"ctrl+k"})
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			km, err := NewKeyMap(tc.overrides)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tc.check(km) {
+				t.Errorf("unexpected keymap: %+v", km)
+			}
+		})
+	}
+}
+
+func TestPrettyKey(t *testing.T) {
+	for k, want := range map[string]string{
+		"ctrl+s":    "⌃S",
+		"shift+tab": "⇧⇥",
+		"enter":     "↩",
+		"q":         "q",
+	} {
+		if got := prettyKey(k); got != want {
+			t.Errorf("prettyKey(%q) = %q, want %q", k, got, want)
+		}
+	}
+}
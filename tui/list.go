@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -16,11 +17,13 @@ import (
 var debounceDuration = 300 * time.Millisecond
 
 type ListItem struct {
-	Id          string
-	Title       string
-	Subtitle    string
-	Preview     string
-	PreviewCmd  func() string
+	Id            string
+	Title         string
+	Subtitle      string
+	Preview       string
+	PreviewFormat string
+	// PreviewCmd runs in a goroutine, it receives the query of the list
+	PreviewCmd  func(context.Context, string) (string, error)
 	Accessories []string
 	Actions     []Action
 }
@@ -28,19 +31,17 @@ type ListItem struct {
 func ParseScriptItem(scriptItem app.ScriptItem) ListItem {
 	actions := make([]Action, len(scriptItem.Actions))
 	for i, scriptAction := range scriptItem.Actions {
-		if i == 0 {
-			scriptAction.Shortcut = "enter"
-		}
 		actions[i] = NewAction(scriptAction)
 	}
 
 	return ListItem{
-		Id:          scriptItem.Id,
-		Title:       scriptItem.Title,
-		Subtitle:    scriptItem.Subtitle,
-		Preview:     scriptItem.Preview,
-		Accessories: scriptItem.Accessories,
-		Actions:     actions,
+		Id:            scriptItem.Id,
+		Title:         scriptItem.Title,
+		Subtitle:      scriptItem.Subtitle,
+		Preview:       scriptItem.Preview,
+		PreviewFormat: scriptItem.Format,
+		Accessories:   scriptItem.Accessories,
+		Actions:       actions,
 	}
 
 }
@@ -65,7 +66,7 @@ func (i ListItem) Render(width int, selected bool) string {
 	titleStyle := lipgloss.NewStyle().Bold(true)
 	if selected {
 		title = fmt.Sprintf("> %s", i.Title)
-		titleStyle = titleStyle.Foreground(lipgloss.Color("13"))
+		titleStyle = styles.Selection
 	} else {
 		title = fmt.Sprintf("  %s", i.Title)
 	}
@@ -104,6 +105,12 @@ type List struct {
 	ShowPreview bool
 
 	previewContent string
+	previewFormat  string
+	previewCache   map[string]string
+	cancelPreview  context.CancelFunc
+	// The spinner is shown while the items are reloaded or the preview runs, until both are done
+	isLoading      bool
+	previewLoading bool
 	filter         Filter
 	viewport       viewport.Model
 }
@@ -121,17 +128,18 @@ func NewList(title string) *List {
 	footer := NewFooter(title)
 
 	return &List{
-		actions:  actions,
-		header:   header,
-		filter:   filter,
-		viewport: viewport,
-		footer:   footer,
+		actions:      actions,
+		header:       header,
+		filter:       filter,
+		viewport:     viewport,
+		footer:       footer,
+		previewCache: make(map[string]string),
 	}
 }
 
 func (c *List) Init() tea.Cmd {
 	if len(c.filter.choices) > 0 {
-		return tea.Batch(c.FilterItems(c.Query()), c.header.Focus())
+		return tea.Batch(c.refreshItems(), c.header.Focus())
 	}
 	return c.header.Focus()
 }
@@ -154,8 +162,13 @@ func (c *List) SetSize(width, height int) {
 
 func (l *List) setPreviewContent(content string) {
 	l.previewContent = content
-	content = lipgloss.NewStyle().Padding(0, 1).Width(l.viewport.Width - 2).Render(content)
-	l.viewport.SetContent(content)
+	width := utils.Max(0, l.viewport.Width-4)
+	rendered, err := renderContent(content, l.previewFormat, width)
+	if err != nil {
+		rendered = wrapContent(content, width)
+	}
+	rendered = lipgloss.NewStyle().Padding(0, 1).Width(l.viewport.Width - 2).Render(rendered)
+	l.viewport.SetContent(rendered)
 }
 
 func (c *List) SetItems(items []ListItem) tea.Cmd {
@@ -164,15 +177,39 @@ func (c *List) SetItems(items []ListItem) tea.Cmd {
 		filterItems[i] = item
 	}
 
+	c.previewCache = make(map[string]string)
 	c.filter.SetItems(filterItems)
-	return c.FilterItems(c.Query())
+	return c.refreshItems()
 }
 
 func (c *List) SetIsLoading(isLoading bool) tea.Cmd {
+	c.isLoading = isLoading
+	return c.updateSpinner()
+}
+
+func (c *List) setPreviewLoading(isLoading bool) tea.Cmd {
+	c.previewLoading = isLoading
+	return c.updateSpinner()
+}
+
+// updateSpinner shows the spinner of the header while a reload or a preview is pending.
+func (c *List) updateSpinner() tea.Cmd {
+	isLoading := c.isLoading || c.previewLoading
+	if isLoading == c.header.isLoading {
+		return nil
+	}
 	return c.header.SetIsLoading(isLoading)
 }
 
-type PreviewContentMsg string
+type PreviewContentMsg struct {
+	Id      string
+	Content string
+	Err     error
+}
+
+type previewDebounceMsg struct {
+	id string
+}
 
 func (l *List) updateActions(item ListItem) tea.Cmd {
 	l.actions.SetTitle(item.Title)
@@ -182,23 +219,76 @@ func (l *List) updateActions(item ListItem) tea.Cmd {
 		l.footer.SetBindings()
 	} else {
 		l.footer.SetBindings(
-			key.NewBinding(key.WithKeys("enter"), key.WithHelp("↩", item.Actions[0].Title)),
-			key.NewBinding(key.WithKeys("tab"), key.WithHelp("⇥", "Show Actions")),
+			key.NewBinding(key.WithKeys(keymap.Confirm.Keys()...), key.WithHelp(keymap.Confirm.Help().Key, item.Actions[0].Title)),
+			keymap.ShowActions,
 		)
 	}
 
-	var cmd tea.Cmd
-	if l.ShowPreview {
+	if !l.ShowPreview {
+		return nil
+	}
+
+	l.stopPreview()
+	l.previewFormat = item.PreviewFormat
+	if item.PreviewCmd == nil {
 		l.setPreviewContent(item.Preview)
+		return nil
 	}
-	return cmd
+
+	if content, ok := l.previewCache[item.Id]; ok {
+		l.setPreviewContent(content)
+		return nil
+	}
+
+	l.setPreviewContent(item.Preview)
+	return tea.Tick(debounceDuration, func(_ time.Time) tea.Msg {
+		return previewDebounceMsg{id: item.Id}
+	})
+}
+
+// runPreview starts the preview command of the selected item,
+// cancelling it if the selection moves before it completes.
+func (l *List) runPreview(item ListItem) tea.Cmd {
+	l.stopPreview()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancelPreview = cancel
+
+	query := l.Query()
+	return tea.Batch(l.setPreviewLoading(true), func() tea.Msg {
+		content, err := item.PreviewCmd(ctx, query)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return PreviewContentMsg{Id: item.Id, Content: content, Err: err}
+	})
+}
+
+func (l *List) stopPreview() {
+	if l.cancelPreview == nil {
+		return
+	}
+
+	l.cancelPreview()
+	l.cancelPreview = nil
+	l.setPreviewLoading(false)
+}
+
+func (l *List) selection() (ListItem, bool) {
+	selection := l.filter.Selection()
+	if selection == nil {
+		return ListItem{}, false
+	}
+
+	item, ok := selection.(ListItem)
+	return item, ok
 }
 
 func (c *List) Update(msg tea.Msg) (Page, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyEscape:
+		switch {
+		case key.Matches(msg, keymap.Back):
 			if c.actions.Focused() {
 				break
 			} else if c.header.input.Value() != "" {
@@ -208,10 +298,10 @@ func (c *List) Update(msg tea.Msg) (Page, tea.Cmd) {
 			} else {
 				return c, PopCmd
 			}
-		case tea.KeyShiftDown:
+		case key.Matches(msg, keymap.ScrollDown):
 			c.viewport.LineDown(1)
 			return c, nil
-		case tea.KeyShiftUp:
+		case key.Matches(msg, keymap.ScrollUp):
 			c.viewport.LineUp(1)
 			return c, nil
 		}
@@ -223,9 +313,29 @@ func (c *List) Update(msg tea.Msg) (Page, tea.Cmd) {
 		return c, NewReloadPageCmd(map[string]app.ScriptInputWithValue{
 			"query": {Value: msg.query},
 		})
+	case previewDebounceMsg:
+		selection, ok := c.selection()
+		if !ok || selection.Id != msg.id {
+			return c, nil
+		}
+
+		return c, c.runPreview(selection)
 	case PreviewContentMsg:
-		c.header.SetIsLoading(false)
-		c.setPreviewContent(string(msg))
+		selection, ok := c.selection()
+		if !ok || selection.Id != msg.Id {
+			return c, nil
+		}
+
+		c.cancelPreview = nil
+		c.setPreviewLoading(false)
+		if msg.Err != nil {
+			c.previewFormat = "ansi"
+			c.setPreviewContent(styles.Error.Render(msg.Err.Error()))
+			return c, nil
+		}
+
+		c.previewCache[msg.Id] = msg.Content
+		c.setPreviewContent(msg.Content)
 		return c, nil
 	}
 
@@ -256,17 +366,9 @@ func (c *List) Update(msg tea.Msg) (Page, tea.Cmd) {
 
 	filter, cmd := c.filter.Update(msg)
 	cmds = append(cmds, cmd)
-	if filter.Selection() == nil {
-		c.actions.SetTitle("")
-		c.actions.SetActions()
-		c.footer.SetBindings()
-		c.setPreviewContent("")
-	} else if c.filter.Selection() == nil || c.filter.Selection().ID() != filter.Selection().ID() {
-		selection := filter.Selection().(ListItem)
-		cmd = c.updateActions(selection)
-		cmds = append(cmds, cmd)
-	}
+	previous := c.filter.Selection()
 	c.filter = filter
+	cmds = append(cmds, c.selectionChanged(previous))
 
 	return c, tea.Batch(cmds...)
 }
@@ -275,12 +377,36 @@ type updateQueryMsg struct {
 	query string
 }
 
+// FilterItems filters the items with the query.
+// The actions and the preview are only updated when the selected item changes, so typing does not restart the preview.
 func (c *List) FilterItems(query string) tea.Cmd {
+	previous := c.filter.Selection()
 	c.filter.FilterItems(query)
-	if c.filter.Selection() != nil {
-		return c.updateActions(c.filter.Selection().(ListItem))
+	return c.selectionChanged(previous)
+}
+
+// refreshItems filters the items again, and updates the actions and the preview since the items may have changed.
+func (c *List) refreshItems() tea.Cmd {
+	c.filter.FilterItems(c.Query())
+	return c.selectionChanged(nil)
+}
+
+// selectionChanged updates the actions and the preview, unless the selected item is still the previous one.
+func (c *List) selectionChanged(previous FilterItem) tea.Cmd {
+	selection := c.filter.Selection()
+	if selection == nil {
+		c.actions.SetTitle("")
+		c.actions.SetActions()
+		c.footer.SetBindings()
+		c.stopPreview()
+		c.setPreviewContent("")
+		return nil
+	}
+
+	if previous != nil && previous.ID() == selection.ID() {
+		return nil
 	}
-	return nil
+	return c.updateActions(selection.(ListItem))
 }
 
 func (c List) View() string {
@@ -293,7 +419,7 @@ func (c List) View() string {
 		for i := 0; i < c.viewport.Height; i++ {
 			separatorChars[i] = "│"
 		}
-		separator := strings.Join(separatorChars, "\n")
+		separator := styles.Border.Render(strings.Join(separatorChars, "\n"))
 		view := lipgloss.JoinHorizontal(lipgloss.Top, c.filter.View(), separator, c.viewport.View())
 
 		return lipgloss.JoinVertical(lipgloss.Top, c.header.View(), view, c.footer.View())
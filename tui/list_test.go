@@ -1,6 +1,9 @@
 package tui
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestItemView(t *testing.T) {
 	type testCase struct {
@@ -54,3 +57,74 @@ func TestItemView(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterItemsKeepsPreview(t *testing.T) {
+	list := NewList("Test")
+	list.ShowPreview = true
+	list.SetSize(80, 20)
+
+	previewCmd := func(context.Context, string) (string, error) {
+		return "preview", nil
+	}
+	list.SetItems([]ListItem{
+		{Id: "alpha", Title: "Alpha", PreviewCmd: previewCmd},
+		{Id: "bravo", Title: "Bravo", PreviewCmd: previewCmd},
+	})
+
+	cancelled := false
+	list.cancelPreview = func() { cancelled = true }
+
+	// The selection is still alpha, so the running preview is kept
+	if cmd := list.FilterItems("al"); cmd != nil || cancelled {
+		t.Errorf("expected the preview to be kept, cancelled: %v", cancelled)
+	}
+	if cmd := list.FilterItems("alp"); cmd != nil || cancelled {
+		t.Errorf("expected the preview to be kept, cancelled: %v", cancelled)
+	}
+
+	if cmd := list.FilterItems("br"); cmd == nil || !cancelled {
+		t.Errorf("expected the preview of the new selection to be scheduled, cancelled: %v", cancelled)
+	}
+	if item, ok := list.selection(); !ok || item.Id != "bravo" {
+		t.Errorf("expected bravo to be selected, got %+v", item)
+	}
+
+	// The preview is cleared when no item matches
+	list.cancelPreview = func() {}
+	list.FilterItems("zzz")
+	if list.cancelPreview != nil {
+		t.Error("expected the preview to be stopped")
+	}
+}
+
+func TestListSpinner(t *testing.T) {
+	list := NewList("Test")
+	list.ShowPreview = true
+	list.SetItems([]ListItem{
+		{Id: "alpha", Title: "Alpha", PreviewCmd: func(context.Context, string) (string, error) {
+			return "preview", nil
+		}},
+	})
+
+	list.SetIsLoading(true)
+	list.runPreview(ListItem{Id: "alpha"})
+
+	// The preview completes while the page is still reloading
+	list.Update(PreviewContentMsg{Id: "alpha", Content: "preview"})
+	if !list.header.isLoading {
+		t.Error("expected the spinner of the reload to be kept")
+	}
+
+	list.SetIsLoading(false)
+	if list.header.isLoading {
+		t.Error("expected the spinner to be hidden")
+	}
+
+	// A cancelled preview does not hide the spinner of a reload either
+	list.runPreview(ListItem{Id: "alpha"})
+	list.SetIsLoading(true)
+	list.stopPreview()
+	if !list.header.isLoading {
+		t.Error("expected the spinner of the reload to be kept")
+	}
+}
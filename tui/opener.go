@@ -0,0 +1,156 @@
+package tui
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/template"
+
+	"github.com/alessio/shellescape"
+	"github.com/sunbeamlauncher/sunbeam/utils"
+)
+
+// Opener is a command used to open the targets matching its criterias.
+// All the criterias defined by the opener must match, an opener without criteria can only be used by name.
+type Opener struct {
+	Name string `yaml:"name"`
+
+	// Scheme matches the scheme of urls, e.g. mailto
+	Scheme string `yaml:"scheme"`
+	// Extension matches the extension of paths, e.g. .md
+	Extension string `yaml:"extension"`
+	// Mime matches the MIME type of the target, wildcards are supported, e.g. image/*
+	Mime string `yaml:"mime"`
+	// Pattern is a regular expression matching the whole target, e.g. https://jira\..*
+	Pattern string `yaml:"pattern"`
+
+	// Exec is the command opening the target, it is referenced as ${{ target }}.
+	// The target is appended to the command if it is not referenced.
+	Exec string `yaml:"exec"`
+	// Terminal gives the terminal to the command until it exits, e.g. for editors
+	Terminal bool `yaml:"terminal"`
+}
+
+func (o Opener) hasCriterias() bool {
+	return o.Scheme != "" || o.Extension != "" || o.Mime != "" || o.Pattern != ""
+}
+
+func (o Opener) Matches(target OpenTarget) bool {
+	if !o.hasCriterias() {
+		return false
+	}
+
+	if o.Scheme != "" && !strings.EqualFold(o.Scheme, target.Scheme) {
+		return false
+	}
+
+	if o.Extension != "" && !strings.EqualFold("."+strings.TrimPrefix(o.Extension, "."), filepath.Ext(target.Path)) {
+		return false
+	}
+
+	if o.Mime != "" {
+		if ok, _ := path.Match(o.Mime, target.Mime()); !ok {
+			return false
+		}
+	}
+
+	if o.Pattern != "" {
+		if ok, _ := regexp.MatchString(fmt.Sprintf("^(?:%s)$", o.Pattern), target.Raw); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (o Opener) Cmd(target OpenTarget) (string, error) {
+	if !strings.Contains(o.Exec, "${{") {
+		return fmt.Sprintf("%s %s", o.Exec, shellescape.Quote(target.String())), nil
+	}
+
+	return utils.RenderString(o.Exec, template.FuncMap{
+		"target": func() string {
+			return shellescape.Quote(target.String())
+		},
+	})
+}
+
+// OpenTarget is either an url or a local path.
+type OpenTarget struct {
+	Raw    string
+	Scheme string
+	// Path is the local path of the target, or the path of the url
+	Path string
+}
+
+func ParseOpenTarget(target string) OpenTarget {
+	// Single letters are windows drive letters, not schemes
+	if u, err := url.Parse(target); err == nil && len(u.Scheme) > 1 {
+		return OpenTarget{Raw: target, Scheme: u.Scheme, Path: u.Path}
+	}
+
+	if resolved, err := utils.ResolvePath(target); err == nil {
+		target = resolved
+	}
+	return OpenTarget{Raw: target, Scheme: "file", Path: target}
+}
+
+func (t OpenTarget) IsLocal() bool {
+	return t.Scheme == "file"
+}
+
+// String returns the path of local targets, and the url of remote ones.
+func (t OpenTarget) String() string {
+	if t.IsLocal() {
+		return t.Path
+	}
+	return t.Raw
+}
+
+// Mime guesses the MIME type of the target from its extension, or from the content of local files.
+func (t OpenTarget) Mime() string {
+	mimeType := mime.TypeByExtension(filepath.Ext(t.Path))
+	if mimeType == "" && t.IsLocal() {
+		if f, err := os.Open(t.Path); err == nil {
+			defer f.Close()
+			buf := make([]byte, 512)
+			n, _ := f.Read(buf)
+			mimeType = http.DetectContentType(buf[:n])
+		}
+	}
+
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	return strings.TrimSpace(mimeType)
+}
+
+// findOpener returns the opener named after the application, or the first one matching the target.
+// Applications without opener are launched directly.
+func findOpener(openers []Opener, target OpenTarget, application string) (Opener, bool) {
+	if application != "" {
+		for _, opener := range openers {
+			if opener.Name == application {
+				return opener, true
+			}
+		}
+
+		if runtime.GOOS == "darwin" {
+			return Opener{Exec: fmt.Sprintf("open -a %s", shellescape.Quote(application))}, true
+		}
+		return Opener{Exec: shellescape.Quote(application)}, true
+	}
+
+	for _, opener := range openers {
+		if opener.Matches(target) {
+			return opener, true
+		}
+	}
+
+	return Opener{}, false
+}
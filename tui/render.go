@@ -0,0 +1,75 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma/quick"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+	"github.com/muesli/reflow/wrap"
+	"github.com/muesli/termenv"
+)
+
+// renderContent formats the content of a detail page or a list preview.
+// Supported formats are text (the default), ansi, markdown and code:<lang>.
+func renderContent(content string, format string, width int) (string, error) {
+	if width <= 0 {
+		return content, nil
+	}
+
+	switch {
+	case format == "" || format == "text":
+		return wrapContent(content, width), nil
+	case format == "ansi":
+		return wrapContent(content, width), nil
+	case format == "markdown":
+		return renderMarkdown(content, width)
+	case strings.HasPrefix(format, "code"):
+		language := strings.TrimPrefix(strings.TrimPrefix(format, "code"), ":")
+		return renderCode(content, language, width)
+	default:
+		return "", fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+func wrapContent(content string, width int) string {
+	// wordwrap only breaks on spaces, so long words still have to be hard wrapped
+	return wrap.String(wordwrap.String(content, width), width)
+}
+
+func renderMarkdown(content string, width int) (string, error) {
+	style := styles.Markdown
+	if lipgloss.ColorProfile() == termenv.Ascii {
+		style = glamour.NoTTYStyleConfig
+	}
+
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStyles(style),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	rendered, err := renderer.Render(content)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Trim(rendered, "\n"), nil
+}
+
+func renderCode(content string, language string, width int) (string, error) {
+	if lipgloss.ColorProfile() == termenv.Ascii || styles.Code == "" {
+		return wrapContent(content, width), nil
+	}
+
+	var builder strings.Builder
+	if err := quick.Highlight(&builder, content, language, "terminal256", styles.Code); err != nil {
+		return "", err
+	}
+
+	return wrap.String(builder.String(), width), nil
+}
@@ -2,6 +2,7 @@ package tui
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -12,9 +13,8 @@ import (
 	"time"
 
 	"github.com/alessio/shellescape"
-	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 	"github.com/muesli/termenv"
 	"github.com/pkg/browser"
 	"github.com/sunbeamlauncher/sunbeam/app"
@@ -22,7 +22,18 @@ import (
 )
 
 type Config struct {
-	Height int
+	Height        int
+	CloseOnAction *bool               `yaml:"closeOnAction"`
+	Clipboard     string              `yaml:"clipboard"`
+	Openers       []Opener            `yaml:"openers"`
+	ImageProtocol string              `yaml:"imageProtocol"`
+	Keymap        map[string][]string `yaml:"keymap"`
+	Theme         string              `yaml:"theme"`
+	Themes        map[string]Theme    `yaml:"themes"`
+	// Registries are the urls of the extension indexes
+	Registries []string `yaml:"registries"`
+	// GithubHost is the host of the GitHub instance, for GitHub Enterprise users
+	GithubHost string `yaml:"githubHost"`
 
 	RootItems []app.RootItem `yaml:"rootItems"`
 }
@@ -39,16 +50,23 @@ type Model struct {
 	exitCmd       *exec.Cmd
 
 	config       *Config
+	dirs         utils.Dirs
+	preferences  *app.Preferences
 	root         Page
 	pages        []Page
 	extensionMap map[string]app.Extension
 	actionChan   chan (map[string]string)
 
+	overlay *ImageOverlay
+
+	toast   *Toast
+	toastId int
+
 	hidden bool
 	exit   bool
 }
 
-func NewModel(config *Config, extensions ...app.Extension) *Model {
+func NewModel(config *Config, dirs utils.Dirs, preferences *app.Preferences, extensions ...app.Extension) *Model {
 	extensionMap := make(map[string]app.Extension)
 	rootItems := make([]app.RootItem, 0)
 	for _, extension := range extensions {
@@ -63,9 +81,9 @@ func NewModel(config *Config, extensions ...app.Extension) *Model {
 		rootItem.Subtitle = extension.Title
 		rootItems = append(rootItems, rootItem)
 	}
-	rootList := NewRootList(rootItems...)
+	rootList := NewRootList(dirs.History(), rootItems...)
 
-	return &Model{extensionMap: extensionMap, root: rootList, config: config}
+	return &Model{extensionMap: extensionMap, root: rootList, config: config, dirs: dirs, preferences: preferences}
 }
 
 func (m *Model) Reset() {
@@ -87,37 +105,83 @@ func (m Model) IsFullScreen() bool {
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	return model, tea.Batch(cmd, m.updateOverlay())
+}
+
+func (m *Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyCtrlC:
+		switch {
+		case key.Matches(msg, keymap.Quit):
 			m.hidden = true
 			m.exit = true
 			return m, tea.Quit
-		case tea.KeyCtrlW:
+		case key.Matches(msg, keymap.Close):
 			m.hidden = true
 			return m, tea.Quit
 		}
 	case tea.WindowSizeMsg:
 		m.SetSize(msg.Width, msg.Height)
+		// The screen is repainted, so the overlay has to be drawn again
+		m.overlay = nil
 		return m, nil
-	case OpenUrlMsg:
+	case drawOverlayMsg:
+		if m.overlay == nil || *m.overlay != msg.overlay {
+			return m, nil
+		}
+		return m, newWriteOverlayCmd(m.overlay)
+	case OpenMsg:
+		target := ParseOpenTarget(msg.Target)
 		if _, ok := os.LookupEnv("SUNBEAM_REMOTE_PIPE"); ok {
+			url := target.Raw
+			if target.IsLocal() {
+				url = fmt.Sprintf("file://%s", target.Path)
+			}
 			m.actionChan <- map[string]string{
 				"action": "open-url",
-				"url":    msg.Url,
+				"url":    url,
 			}
 
-			m.hidden = true
-			return m, tea.Quit
+			return m.actionDone("Opened", msg.CloseOnAction, msg.Reload)
+		}
+
+		if opener, ok := findOpener(m.config.Openers, target, msg.Application); ok {
+			commandString, err := opener.Cmd(target)
+			if err != nil {
+				return m, NewErrorToastCmd(fmt.Errorf("invalid opener: %s", err))
+			}
+
+			command := exec.Command("sh", "-c", commandString)
+			if opener.Terminal {
+				// The opener takes over the terminal, sunbeam resumes once it exits
+				return m, tea.ExecProcess(command, func(err error) tea.Msg {
+					if err != nil {
+						return processExitMsg{result: ToastMsg{Level: ToastError, Text: fmt.Sprintf("failed to open %s: %s", target, err)}}
+					}
+					return processExitMsg{result: openerExitMsg{closeOnAction: msg.CloseOnAction, reload: msg.Reload}}
+				})
+			}
+
+			if err := command.Start(); err != nil {
+				return m, NewErrorToastCmd(fmt.Errorf("failed to open %s: %s", target, err))
+			}
+			go command.Wait()
+
+			return m.actionDone("Opened", msg.CloseOnAction, msg.Reload)
+		}
+
+		var err error
+		if target.IsLocal() {
+			err = browser.OpenFile(target.Path)
+		} else {
+			err = browser.OpenURL(target.Raw)
 		}
-		err := browser.OpenURL(msg.Url)
 		if err != nil {
-			return m, NewErrorCmd(err)
+			return m, NewErrorToastCmd(fmt.Errorf("failed to open %s: %s", target, err))
 		}
 
-		m.hidden = true
-		return m, tea.Quit
+		return m.actionDone("Opened", msg.CloseOnAction, msg.Reload)
 	case CopyTextMsg:
 		if _, ok := os.LookupEnv("SUNBEAM_REMOTE_PIPE"); ok {
 			m.actionChan <- map[string]string{
@@ -125,27 +189,25 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				"text":   msg.Text,
 			}
 
-			m.hidden = true
-			return m, tea.Quit
+			return m.actionDone("Copied to clipboard", msg.CloseOnAction, msg.Reload)
 		}
-		err := clipboard.WriteAll(msg.Text)
+		err := utils.CopyText(msg.Text, m.config.Clipboard)
 		if err != nil {
-			return m, NewErrorCmd(fmt.Errorf("failed to copy text to clipboard: %s", err))
+			return m, NewErrorToastCmd(fmt.Errorf("failed to copy text to clipboard: %s", err))
 		}
 
-		m.hidden = true
-		return m, tea.Quit
+		return m.actionDone("Copied to clipboard", msg.CloseOnAction, msg.Reload)
 	case ShowPrefMsg:
 		extension, ok := m.extensionMap[msg.Extension]
 		if !ok {
-			return m, NewErrorCmd(fmt.Errorf("extension %s not found", msg.Extension))
+			return m, NewErrorToastCmd(fmt.Errorf("extension %s not found", msg.Extension))
 		}
 		script, ok := extension.Commands[msg.Script]
 		if !ok {
-			return m, NewErrorCmd(fmt.Errorf("script %s not found", msg.Script))
+			return m, NewErrorToastCmd(fmt.Errorf("script %s not found", msg.Script))
 		}
 
-		pref := NewPreferenceForm(extension, script)
+		pref := NewPreferenceForm(extension, script, m.preferences)
 
 		cmd := m.Push(pref)
 
@@ -153,7 +215,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case RunScriptMsg:
 		extension, ok := m.extensionMap[msg.Extension]
 		if !ok {
-			return m, NewErrorCmd(fmt.Errorf("extension %s not found", msg.Extension))
+			return m, NewErrorToastCmd(fmt.Errorf("extension %s not found", msg.Extension))
 		}
 
 		if len(extension.Requirements) > 0 {
@@ -168,36 +230,123 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		script, ok := extension.Commands[msg.Script]
 		if !ok {
-			return m, NewErrorCmd(fmt.Errorf("script %s not found", msg.Script))
+			return m, NewErrorToastCmd(fmt.Errorf("script %s not found", msg.Script))
 		}
 
-		runner := NewScriptRunner(extension, script, msg.With)
-
+		// The action can override how the output of the script is handled
 		if msg.OnSuccess != "" {
 			script.OnSuccess = msg.OnSuccess
 		}
+		if msg.Confirmed {
+			script.Confirm = app.Confirm{}
+		}
+		runner := NewScriptRunner(extension, script, msg.With, m.preferences)
+		runner.SetContext(pageContext(m.currentPage()))
+
+		if script.OnSuccess == "replace-page" && runner.ShowsPage() {
+			cmd := m.Replace(runner)
+			return m, cmd
+		}
 		cmd := m.Push(runner)
 		return m, cmd
 	case ExecCommandMsg:
 		command := exec.Command("sh", "-c", msg.Exec)
 		command.Dir = msg.Directory
 		command.Env = os.Environ()
+		// The variables set by the runner take precedence
+		query, selection := pageContext(m.currentPage())
+		command.Env = append(command.Env, sessionEnv(m.width, m.pageHeight(), query, selection)...)
 		command.Env = append(command.Env, msg.Env...)
 
-		if msg.OnSuccess == "" {
+		release, err := attachPreferences(command, msg.Preferences)
+		if err != nil {
+			return m, NewErrorCmd(err)
+		}
+
+		// The runner only collected the inputs of the command, it is not needed anymore
+		var transientRoot bool
+		if runner, ok := m.currentPage().(*ScriptRunner); ok && !runner.ShowsPage() {
+			transientRoot = len(m.pages) == 0
+			if !transientRoot {
+				m.Pop()
+			} else if msg.OnSuccess == "toast" {
+				// There is no page to display the toast on top of
+				msg.OnSuccess = "replace-page"
+			}
+		}
+
+		// Interactive commands only return to sunbeam if there is a page to go back to
+		if (msg.OnSuccess == "" && !msg.Interactive) || (msg.Interactive && transientRoot) {
 			m.exitCmd = command
 			m.hidden = true
 			return m, tea.Quit
 		}
 
+		if msg.Interactive {
+			return m, tea.ExecProcess(command, func(err error) tea.Msg {
+				release()
+				return processExitMsg{result: msg.OnExitMsg(err)}
+			})
+		}
+
 		return m, func() tea.Msg {
-			output, err := command.Output()
+			defer release()
+			output, toasts, err := runCommand(command)
 			if err != nil {
-				return err
+				return withToasts(msg.OnErrorMsg(err), toasts)
 			}
 
-			return msg.OnSuccessMsg(string(output))
+			return withToasts(msg.OnSuccessMsg(output), toasts)
 		}
+	case openerExitMsg:
+		return m.actionDone("Opened", msg.closeOnAction, msg.reload)
+	case processExitMsg:
+		// The screen is repainted once the program resumes
+		m.overlay = nil
+		return m, func() tea.Msg {
+			return msg.result
+		}
+	case ConfirmMsg:
+		cmd := m.Push(NewConfirm(msg.Message, msg.Cmd))
+		return m, cmd
+	case cancelConfirmMsg:
+		m.Pop()
+		// The runner was only waiting for the confirmation to run the command
+		if runner, ok := m.currentPage().(*ScriptRunner); ok && !runner.ShowsPage() {
+			if len(m.pages) == 0 {
+				m.hidden = true
+				return m, tea.Quit
+			}
+			m.Pop()
+		}
+		return m, nil
+	case ShowOutputMsg:
+		detail := NewDetail(msg.Title)
+		detail.SetContent(msg.Output)
+
+		if runner, ok := m.currentPage().(*ScriptRunner); msg.Replace || (ok && !runner.ShowsPage()) {
+			cmd := m.Replace(detail)
+			return m, cmd
+		}
+		cmd := m.Push(detail)
+		return m, cmd
+	case ToastMsg:
+		// A runner without page has nothing to display the toast on top of
+		if runner, ok := m.currentPage().(*ScriptRunner); ok && !runner.ShowsPage() && msg.Level == ToastError {
+			return m, NewErrorCmd(errors.New(msg.Text))
+		}
+
+		m.toastId++
+		m.toast = &Toast{
+			Level: msg.Level,
+			Text:  msg.Text,
+		}
+		return m, newDismissToastCmd(m.toastId)
+	case dismissToastMsg:
+		if msg.id == m.toastId {
+			m.toast = nil
+		}
+		return m, nil
 	case pushMsg:
 		m.hidden = false
 		cmd := m.Push(msg.container)
@@ -212,16 +361,10 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case error:
 		m.hidden = false
 		detail := NewDetail("Error")
-		detail.SetSize(m.width, m.pageHeight())
-		detail.SetContent(msg.Error())
+		detail.SetContent(styles.Error.Render(msg.Error()))
 
-		if len(m.pages) == 0 {
-			m.root = detail
-		} else {
-			m.pages[len(m.pages)-1] = detail
-		}
-
-		return m, detail.Init()
+		cmd := m.Replace(detail)
+		return m, cmd
 	}
 
 	// Update the current page
@@ -237,6 +380,41 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// updateOverlay schedules the drawing of the current page image, or clears the previous one.
+func (m *Model) updateOverlay() tea.Cmd {
+	if imageProtocol == ImageProtocolBlocks {
+		return nil
+	}
+
+	var overlay ImageOverlay
+	var ok bool
+	if page, isOverlayPage := m.currentPage().(OverlayPage); isOverlayPage && !m.hidden {
+		overlay, ok = page.Overlay()
+	}
+
+	if !ok {
+		if m.overlay != nil {
+			m.overlay = nil
+			return newWriteOverlayCmd(nil)
+		}
+		return nil
+	}
+
+	if m.overlay != nil && *m.overlay == overlay {
+		return nil
+	}
+
+	m.overlay = &overlay
+	return newDrawOverlayCmd(overlay)
+}
+
+func (m *Model) currentPage() Page {
+	if len(m.pages) > 0 {
+		return m.pages[len(m.pages)-1]
+	}
+	return m.root
+}
+
 type ShowPrefMsg struct {
 	Extension string
 	Script    string
@@ -247,13 +425,12 @@ func (m *Model) View() string {
 		return ""
 	}
 
-	if len(m.pages) > 0 {
-		currentPage := m.pages[len(m.pages)-1]
-		return currentPage.View()
+	view := m.currentPage().View()
+	if m.toast != nil {
+		view = overlayToast(view, *m.toast, m.width)
 	}
 
-	return m.root.View()
-
+	return view
 }
 
 func (m *Model) SetSize(width, height int) {
@@ -296,6 +473,36 @@ func (m *Model) Push(page Page) tea.Cmd {
 	return page.Init()
 }
 
+// actionDone exits the program once an action has run, unless closeOnAction is disabled.
+// In that case the page stack is kept, a toast confirms the action and the page is optionally reloaded.
+func (m *Model) actionDone(message string, closeOnAction *bool, reload bool) (tea.Model, tea.Cmd) {
+	if closeOnAction == nil {
+		closeOnAction = m.config.CloseOnAction
+	}
+
+	if closeOnAction == nil || *closeOnAction {
+		m.hidden = true
+		return m, tea.Quit
+	}
+
+	cmds := []tea.Cmd{NewToastCmd(ToastSuccess, message)}
+	if reload {
+		cmds = append(cmds, NewReloadPageCmd(nil))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// Replace swaps the current page with a new one.
+func (m *Model) Replace(page Page) tea.Cmd {
+	page.SetSize(m.width, m.pageHeight())
+	if len(m.pages) == 0 {
+		m.root = page
+	} else {
+		m.pages[len(m.pages)-1] = page
+	}
+	return page.Init()
+}
+
 func (m *Model) Pop() {
 	if len(m.pages) > 0 {
 		m.pages = m.pages[:len(m.pages)-1]
@@ -330,10 +537,7 @@ func loadHistory(historyPath string) map[string]int64 {
 	return history
 }
 
-func NewRootList(rootItems ...app.RootItem) Page {
-	stateDir := path.Join(os.Getenv("HOME"), ".local", "state", "sunbeam")
-	historyPath := path.Join(stateDir, "history.json")
-
+func NewRootList(historyPath string, rootItems ...app.RootItem) Page {
 	history := loadHistory(historyPath)
 
 	list := NewList("Sunbeam")
@@ -365,12 +569,11 @@ func NewRootList(rootItems ...app.RootItem) Page {
 			Subtitle: rootItem.Subtitle,
 			Actions: []Action{
 				{
-					Title:    "Run Script",
-					Shortcut: "enter",
+					Title: "Run Script",
 					Cmd: func() tea.Msg {
 						history[itemShellCommand] = time.Now().Unix()
-						if _, err := os.Stat(stateDir); os.IsNotExist(err) {
-							os.MkdirAll(stateDir, 0755)
+						if _, err := os.Stat(path.Dir(historyPath)); os.IsNotExist(err) {
+							os.MkdirAll(path.Dir(historyPath), 0755)
 						}
 
 						data, _ := json.Marshal(history)
@@ -415,22 +618,17 @@ func Draw(model *Model) (err error) {
 		}
 		defer f.Close()
 	} else {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return err
-		}
-		logDir := path.Join(home, ".local", "state", "sunbeam")
-		if _, err := os.Stat(logDir); os.IsNotExist(err) {
-			err = os.MkdirAll(path.Join(home, ".local", "state", "sunbeam"), 0755)
+		logPath := model.dirs.Log()
+		if _, err := os.Stat(path.Dir(logPath)); os.IsNotExist(err) {
+			err = os.MkdirAll(path.Dir(logPath), 0755)
 			if err != nil {
 				return err
 			}
 		}
-		tea.LogToFile(path.Join(logDir, "sunbeam.log"), "")
+		tea.LogToFile(logPath, "")
 	}
 
-	// Disable the background detection since we are only using ANSI colors
-	lipgloss.SetHasDarkBackground(true)
+	initImageProtocol(model.config.ImageProtocol, model.IsFullScreen())
 
 	pipeFile, isRemote := os.LookupEnv("SUNBEAM_REMOTE_PIPE")
 	if isRemote {
@@ -463,14 +661,22 @@ func Draw(model *Model) (err error) {
 	}
 
 	for {
-		var p *tea.Program
+		options := make([]tea.ProgramOption, 0)
 		if model.IsFullScreen() {
-			p = tea.NewProgram(model, tea.WithAltScreen())
-		} else {
-			p = tea.NewProgram(model)
+			options = append(options, tea.WithAltScreen())
+		}
+		if imageProtocol != ImageProtocolBlocks {
+			options = append(options, tea.WithOutput(overlayOutput))
+		}
+
+		p := tea.NewProgram(model, options...)
+		stopResize := func() {}
+		if imageProtocol != ImageProtocolBlocks {
+			stopResize = forwardResize(p)
 		}
 
 		m, err := p.Run()
+		stopResize()
 		if err != nil {
 			return err
 		}
@@ -1,34 +1,49 @@
 package tui
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/sunbeamlauncher/sunbeam/app"
+	"github.com/sunbeamlauncher/sunbeam/utils"
 )
 
 type ScriptRunner struct {
 	width, height int
 	currentView   string
 
-	extension app.Extension
-	with      map[string]app.ScriptInputWithValue
-	environ   []string
+	extension   app.Extension
+	with        map[string]app.ScriptInputWithValue
+	environ     []string
+	preferences *app.Preferences
+	// preferenceValues are the typed values of the preferences, sent as a json document if the extension requests it
+	preferenceValues map[string]any
+
+	// query and selection describe the page the script was started from
+	query     string
+	selection string
 
 	list   *List
 	detail *Detail
 	form   *Form
 
 	script app.Command
+	// refresh bypasses the output cache, e.g. when the page is reloaded
+	refresh bool
+	// dirsReady is set once the directories of the extension are created
+	dirsReady bool
 }
 
-func NewScriptRunner(extension app.Extension, script app.Command, with map[string]app.ScriptInputWithValue) *ScriptRunner {
+func NewScriptRunner(extension app.Extension, script app.Command, with map[string]app.ScriptInputWithValue, preferences *app.Preferences) *ScriptRunner {
 	mergedParams := make(map[string]app.ScriptInputWithValue)
 
 	for _, scriptParam := range script.Inputs {
@@ -51,62 +66,311 @@ func NewScriptRunner(extension app.Extension, script app.Command, with map[strin
 	}
 
 	return &ScriptRunner{
-		extension: extension,
-		script:    script,
-		with:      mergedParams,
+		extension:   extension,
+		script:      script,
+		with:        mergedParams,
+		preferences: preferences,
 	}
 }
 
+// ShowsPage reports whether the output of the script is displayed as a list or a detail page.
+// Otherwise the runner only collects the missing inputs before the script is executed.
+func (c ScriptRunner) ShowsPage() bool {
+	return c.script.OnSuccess == "push-page" || (c.script.OnSuccess == "replace-page" && c.script.Page.Type != "")
+}
+
 func (c *ScriptRunner) Init() tea.Cmd {
 	return c.Run()
 }
 
 type CommandOutput string
 
-func (c ScriptRunner) ScriptCmd() tea.Msg {
+// ScriptCmd returns the command running the script.
+// The state of the runner is read synchronously, since the returned command runs concurrently with Update.
+func (c *ScriptRunner) ScriptCmd() tea.Cmd {
 	with := make(map[string]any)
 
 	for key, param := range c.with {
 		value, err := param.GetValue()
 		if err != nil {
-			return err
+			return NewErrorCmd(err)
 		}
 		with[key] = value
 	}
 
 	commandString, err := c.script.Cmd(with)
 	if err != nil {
-		return err
+		return NewErrorCmd(err)
 	}
 
-	if c.script.OnSuccess != "push-page" {
-		return ExecCommandMsg{
+	env := c.env()
+	preferences := c.preferencesFd()
+
+	if !c.ShowsPage() {
+		msg := ExecCommandMsg{
+			Title:     c.extension.Title,
 			Exec:      commandString,
 			Directory: c.extension.Root,
-			Env:       c.environ,
+			Env:       env,
 			OnSuccess: c.script.OnSuccess,
+
+			Preferences: preferences,
+
+			Interactive: c.script.Interactive,
+		}
+
+		if c.script.Confirm.Required {
+			confirm := ConfirmMsg{
+				Message: c.script.Confirm.Message,
+				Cmd: func() tea.Msg {
+					return msg
+				},
+			}
+			return func() tea.Msg {
+				return confirm
+			}
+		}
+
+		return func() tea.Msg {
+			return msg
 		}
 	}
 
-	command := exec.Command("sh", "-c", commandString)
-	if c.script.Page.Type == "generator" {
-		command.Stdin = strings.NewReader(c.list.Query())
+	var stdin *string
+	if c.script.Page.Type == "generator" && c.list != nil {
+		query := c.list.Query()
+		stdin = &query
 	}
 
-	command.Dir = c.extension.Root
-	command.Env = os.Environ()
-	command.Env = append(command.Env, c.environ...)
+	// The preferences and the session (query, selection, size) reach the script through its environment, so they are part of the key
+	cacheKey := outputCacheKey(commandString, env, c.preferencesDocument())
+	extension, script, refresh := c.extension, c.script, c.refresh
+	return func() tea.Msg {
+		command := exec.Command("sh", "-c", commandString)
+		if stdin != nil {
+			command.Stdin = strings.NewReader(*stdin)
+		}
 
-	output, err := command.Output()
-	if err != nil {
-		var exitErr *exec.ExitError
-		if ok := errors.As(err, &exitErr); ok {
-			return fmt.Errorf("command failed with exit code %d, error:\n%s", exitErr.ExitCode(), exitErr.Stderr)
+		command.Dir = extension.Root
+		command.Env = os.Environ()
+		command.Env = append(command.Env, env...)
+
+		if script.Cache != nil && script.Page.Type != "generator" {
+			return cachedCmd(extension, *script.Cache, cacheKey, command, preferences, refresh)
+		}
+
+		output, toasts, err := runWithPreferences(command, preferences)
+		if err != nil {
+			return withToasts(commandError(err), toasts)
 		}
+
+		return withToasts(CommandOutput(output), toasts)
+	}
+}
+
+// cachedCmd serves the output of the command from the cache of the extension.
+// Stale outputs are displayed while the command runs again in the background.
+func cachedCmd(extension app.Extension, commandCache app.CommandCache, cacheKey string, command *exec.Cmd, preferences []byte, refresh bool) tea.Msg {
+	ttl, err := commandCache.Duration()
+	if err != nil {
+		return fmt.Errorf("invalid cache ttl: %w", err)
+	}
+
+	cache, err := extension.OutputCache()
+	if err != nil {
 		return err
 	}
 
-	return CommandOutput(string(output))
+	runAndStore := func() (string, []ToastMsg, error) {
+		output, toasts, err := runWithPreferences(command, preferences)
+		if err != nil {
+			return "", toasts, err
+		}
+
+		return output, toasts, cache.Set(cacheKey, output)
+	}
+
+	output, age, ok := cache.Get(cacheKey)
+	if !ok || refresh {
+		output, toasts, err := runAndStore()
+		if err != nil {
+			return withToasts(commandError(err), toasts)
+		}
+		return withToasts(CommandOutput(output), toasts)
+	}
+
+	if age < ttl {
+		return CommandOutput(output)
+	}
+
+	// The stale output must be displayed before the refreshed one
+	return tea.Sequence(
+		func() tea.Msg {
+			return CommandOutput(output)
+		},
+		func() tea.Msg {
+			output, toasts, err := runAndStore()
+			// The stale output is still displayed, so failures are only reported
+			if err != nil {
+				return withToasts(ToastMsg{Level: ToastError, Text: toastError(err)}, toasts)
+			}
+			return withToasts(CommandOutput(output), toasts)
+		},
+	)()
+}
+
+// outputCacheKey identifies the output of a command, along with the environment and the preferences it runs with.
+func outputCacheKey(commandString string, env []string, preferences []byte) string {
+	hash := sha256.New()
+	for _, part := range append([]string{string(preferences)}, env...) {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+
+	return fmt.Sprintf("%s\x00%x", commandString, hash.Sum(nil))
+}
+
+// SetContext records the query and the selected item of the page the script was started from.
+func (c *ScriptRunner) SetContext(query string, selection string) {
+	c.query = query
+	c.selection = selection
+}
+
+// env returns the SUNBEAM_* variables passed to the scripts, followed by the preferences.
+// Once the runner displays a list, its query and selection take precedence over the ones of the previous page.
+func (c ScriptRunner) env() []string {
+	query, selection := c.query, c.selection
+	if c.list != nil {
+		query = c.list.Query()
+		if item, ok := c.list.selection(); ok {
+			selection = item.Id
+		}
+	}
+
+	return append(sessionEnv(c.width, c.height, query, selection), c.extensionEnv()...)
+}
+
+// extensionEnv returns the variables describing the extension and its preferences.
+func (c ScriptRunner) extensionEnv() []string {
+	env := []string{
+		fmt.Sprintf("SUNBEAM_EXTENSION=%s", c.extension.Name),
+		fmt.Sprintf("SUNBEAM_EXTENSION_ROOT=%s", c.extension.Root),
+		fmt.Sprintf("SUNBEAM_COMMAND=%s", c.script.Name),
+	}
+
+	// The directories are only advertised once they exist
+	if c.dirsReady {
+		dataDir, _ := c.extension.DataDir()
+		cacheDir, _ := c.extension.CacheDir()
+		stateDir, _ := c.extension.StateDir()
+		env = append(env,
+			fmt.Sprintf("SUNBEAM_DATA_DIR=%s", dataDir),
+			fmt.Sprintf("SUNBEAM_CACHE_DIR=%s", cacheDir),
+			fmt.Sprintf("SUNBEAM_STATE_DIR=%s", stateDir),
+		)
+	}
+
+	env = append(env, c.environ...)
+	if c.extension.PreferencesJson == "env" {
+		env = append(env, fmt.Sprintf("SUNBEAM_PREFERENCES=%s", c.preferencesDocument()))
+	}
+
+	return env
+}
+
+// sessionEnv returns the SUNBEAM_* variables which do not depend on an extension.
+func sessionEnv(width, height int, query string, selection string) []string {
+	remote := 0
+	if _, ok := os.LookupEnv("SUNBEAM_REMOTE_PIPE"); ok {
+		remote = 1
+	}
+
+	return []string{
+		fmt.Sprintf("SUNBEAM_QUERY=%s", query),
+		fmt.Sprintf("SUNBEAM_SELECTION=%s", selection),
+		fmt.Sprintf("SUNBEAM_TERM_WIDTH=%d", width),
+		fmt.Sprintf("SUNBEAM_TERM_HEIGHT=%d", height),
+		fmt.Sprintf("SUNBEAM_REMOTE=%d", remote),
+	}
+}
+
+// pageContext returns the query and the id of the selected item of a page.
+func pageContext(page Page) (query string, selection string) {
+	var list *List
+	switch page := page.(type) {
+	case *List:
+		list = page
+	case *ScriptRunner:
+		if page.currentView != "list" {
+			return page.query, page.selection
+		}
+		list = page.list
+	default:
+		return "", ""
+	}
+
+	if item, ok := list.selection(); ok {
+		selection = item.Id
+	}
+	return list.Query(), selection
+}
+
+func commandError(err error) error {
+	var exitErr *exec.ExitError
+	if ok := errors.As(err, &exitErr); ok {
+		return fmt.Errorf("command failed with exit code %d, error:\n%s", exitErr.ExitCode(), exitErr.Stderr)
+	}
+	return err
+}
+
+// toastError summarizes a command failure in a single line, using the last line of stderr.
+func toastError(err error) string {
+	var exitErr *exec.ExitError
+	if ok := errors.As(err, &exitErr); !ok {
+		return err.Error()
+	}
+
+	stderr := strings.TrimSpace(string(exitErr.Stderr))
+	if stderr == "" {
+		return fmt.Sprintf("command failed with exit code %d", exitErr.ExitCode())
+	}
+
+	lines := strings.Split(stderr, "\n")
+	return fmt.Sprintf("command failed with exit code %d: %s", exitErr.ExitCode(), lines[len(lines)-1])
+}
+
+// PreviewCmd returns the preview command of a list item.
+// The environment is computed when the list is populated, only the query of the list is read when the preview runs.
+func (c ScriptRunner) PreviewCmd(previewCommand app.PreviewCommand, id string) func(context.Context, string) (string, error) {
+	commandString, err := previewCommand.Cmd(c.extension.Commands)
+	extensionEnv := c.extensionEnv()
+	preferences := c.preferencesFd()
+	width, height, dir := c.width, c.height, c.extension.Root
+
+	return func(ctx context.Context, query string) (string, error) {
+		if err != nil {
+			return "", err
+		}
+
+		command := exec.CommandContext(ctx, "sh", "-c", commandString)
+		command.Dir = dir
+		command.Env = os.Environ()
+		command.Env = append(command.Env, sessionEnv(width, height, query, id)...)
+		command.Env = append(command.Env, extensionEnv...)
+
+		release, err := attachPreferences(command, preferences)
+		if err != nil {
+			return "", err
+		}
+		defer release()
+
+		output, err := command.Output()
+		if err != nil {
+			return "", commandError(err)
+		}
+
+		return string(output), nil
+	}
 }
 
 func (c *ScriptRunner) CheckMissingParameters() []FormItem {
@@ -139,31 +403,107 @@ func (c ScriptRunner) Preferences() map[string]app.ScriptInputWithValue {
 	return preferenceMap
 }
 
-func (c *ScriptRunner) checkPreferences() (environ []string, missing []FormItem) {
-	envMap := make(map[string]struct{})
-	for _, env := range os.Environ() {
-		pair := strings.SplitN(env, "=", 2)
-		envMap[pair[0]] = struct{}{}
-	}
-
+// checkPreferences resolves the preferences of the command, falling back to their default value.
+func (c *ScriptRunner) checkPreferences() (missing []FormItem, err error) {
+	c.environ = nil
+	c.preferenceValues = make(map[string]any)
 	for name, param := range c.Preferences() {
-		if _, ok := envMap[name]; ok {
+		var value any
+		pref, ok := c.preferences.Get(c.extension.Name, c.script.Name, name)
+		if ok {
+			value = pref.Value
+		} else if param.Default.Defined {
+			value = param.Default.Value
+		} else {
+			missing = append(missing, NewFormItem(param.ScriptInput))
 			continue
 		}
 
-		if pref, ok := keyStore.GetPreference(c.extension.Name, c.script.Name, name); ok {
-			environ = append(environ, fmt.Sprintf("%s=%s", name, pref.Value))
+		typed, err := param.TypedValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preference: %w", err)
+		}
+		c.preferenceValues[name] = typed
+
+		// Environment variables are already inherited by the command
+		if ok && pref.Scope == app.PreferenceScopeEnv {
 			continue
 		}
 
-		missing = append(missing, NewFormItem(param.ScriptInput))
+		encoded, err := param.EncodeValue(typed)
+		if err != nil {
+			return nil, err
+		}
+		c.environ = append(c.environ, fmt.Sprintf("%s=%s", name, encoded))
+	}
+
+	return missing, nil
+}
+
+// preferencesDocument returns the json document containing all the preferences.
+func (c ScriptRunner) preferencesDocument() []byte {
+	document, err := json.Marshal(c.preferenceValues)
+	if err != nil {
+		return nil
+	}
+	return document
+}
+
+// preferencesFd returns the document sent on a file descriptor, if the extension requests it.
+func (c ScriptRunner) preferencesFd() []byte {
+	if c.extension.PreferencesJson != "fd" {
+		return nil
+	}
+	return c.preferencesDocument()
+}
+
+// runWithPreferences executes a command displaying a page, the preferences document is sent on a file descriptor if it is not nil.
+func runWithPreferences(command *exec.Cmd, preferences []byte) (string, []ToastMsg, error) {
+	release, err := attachPreferences(command, preferences)
+	if err != nil {
+		return "", nil, err
 	}
+	defer release()
 
-	return environ, missing
+	return runCommand(command)
+}
+
+// attachPreferences sends the preferences document to the command on an extra file descriptor, referenced by SUNBEAM_PREFERENCES_FD.
+// The returned function releases the descriptor once the command has run.
+func attachPreferences(command *exec.Cmd, document []byte) (func(), error) {
+	if document == nil {
+		return func() {}, nil
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	command.ExtraFiles = append(command.ExtraFiles, reader)
+	// The extra files start after stdin, stdout and stderr
+	command.Env = append(command.Env, fmt.Sprintf("SUNBEAM_PREFERENCES_FD=%d", 2+len(command.ExtraFiles)))
+
+	go func() {
+		writer.Write(document)
+		writer.Close()
+	}()
+
+	return func() {
+		reader.Close()
+	}, nil
 }
 
 func (c *ScriptRunner) Run() tea.Cmd {
-	environ, missing := c.checkPreferences()
+	// The directories are created once, instead of on every execution
+	if !c.dirsReady {
+		c.dirsReady = c.extension.EnsureDirs() == nil
+	}
+
+	missing, err := c.checkPreferences()
+	if err != nil {
+		return NewErrorCmd(err)
+	}
 	if len(missing) > 0 {
 		c.currentView = "form"
 		title := fmt.Sprintf("%s · Preferences", c.extension.Title)
@@ -171,7 +511,6 @@ func (c *ScriptRunner) Run() tea.Cmd {
 		c.form.SetSize(c.width, c.height)
 		return c.form.Init()
 	}
-	c.environ = environ
 
 	formItems := c.CheckMissingParameters()
 
@@ -184,32 +523,32 @@ func (c *ScriptRunner) Run() tea.Cmd {
 		return c.form.Init()
 	}
 
-	if c.script.OnSuccess != "push-page" {
+	if !c.ShowsPage() {
 		if c.form != nil {
 			cmd := c.form.SetIsLoading(true)
-			return tea.Batch(cmd, c.ScriptCmd)
+			return tea.Batch(cmd, c.ScriptCmd())
 		}
-		return c.ScriptCmd
+		return c.ScriptCmd()
 	}
 
 	if c.script.Page.Type == "detail" {
 		c.currentView = "detail"
 		if c.detail != nil {
 			cmd := c.detail.SetIsLoading(true)
-			return tea.Batch(cmd, c.ScriptCmd)
+			return tea.Batch(cmd, c.ScriptCmd())
 		}
 
 		c.detail = NewDetail(c.extension.Title)
 		c.detail.SetSize(c.width, c.height)
 		cmd := c.detail.SetIsLoading(true)
-		return tea.Batch(c.ScriptCmd, cmd, c.detail.Init())
+		return tea.Batch(c.ScriptCmd(), cmd, c.detail.Init())
 	}
 
 	if c.script.Page.Type == "list" {
 		c.currentView = "list"
 		if c.list != nil {
 			cmd := c.list.SetIsLoading(true)
-			return tea.Batch(cmd, c.ScriptCmd)
+			return tea.Batch(cmd, c.ScriptCmd())
 		}
 		c.list = NewList(c.extension.Title)
 		if c.script.Page.IsGenerator {
@@ -222,12 +561,30 @@ func (c *ScriptRunner) Run() tea.Cmd {
 		c.list.SetSize(c.width, c.height)
 
 		cmd := c.list.SetIsLoading(true)
-		return tea.Batch(c.ScriptCmd, c.list.Init(), cmd)
+		return tea.Batch(c.ScriptCmd(), c.list.Init(), cmd)
 	}
 
 	return NewErrorCmd(fmt.Errorf("unknown page type: %s", c.script.Page.Type))
 }
 
+// resolvePath resolves a path relative to the extension root.
+func (c ScriptRunner) resolvePath(target string) (string, error) {
+	if strings.HasPrefix(target, "~") {
+		return utils.ResolvePath(target)
+	}
+	if filepath.IsAbs(target) {
+		return target, nil
+	}
+	return filepath.Join(c.extension.Root, target), nil
+}
+
+func (c *ScriptRunner) Overlay() (ImageOverlay, bool) {
+	if c.currentView != "detail" || c.detail == nil {
+		return ImageOverlay{}, false
+	}
+	return c.detail.Overlay()
+}
+
 func (c *ScriptRunner) SetSize(width, height int) {
 	c.width, c.height = width, height
 	switch c.currentView {
@@ -245,12 +602,19 @@ func (c *ScriptRunner) Update(msg tea.Msg) (Page, tea.Cmd) {
 	case CommandOutput:
 		switch c.script.Page.Type {
 		case "detail":
-			var detail app.Detail
-			err := json.Unmarshal([]byte(msg), &detail)
+			detail, err := app.ParseDetail(string(msg))
 			if err != nil {
 				return c, NewErrorCmd(err)
 			}
 
+			if detail.Image != "" && !strings.HasPrefix(detail.Image, "data:") {
+				image, err := c.resolvePath(detail.Image)
+				if err != nil {
+					return c, NewErrorCmd(err)
+				}
+				detail.Image = image
+			}
+
 			c.detail.SetIsLoading(false)
 			cmd := c.detail.SetDetail(detail)
 			c.SetSize(c.width, c.height)
@@ -277,6 +641,9 @@ func (c *ScriptRunner) Update(msg tea.Msg) (Page, tea.Cmd) {
 				}
 
 				listItems[i] = ParseScriptItem(scriptItem)
+				if scriptItem.PreviewCommand != nil {
+					listItems[i].PreviewCmd = c.PreviewCmd(*scriptItem.PreviewCommand, scriptItem.Id)
+				}
 			}
 
 			cmd := c.list.SetItems(listItems)
@@ -286,35 +653,7 @@ func (c *ScriptRunner) Update(msg tea.Msg) (Page, tea.Cmd) {
 	case SubmitMsg:
 		switch msg.Name {
 		case "preferences":
-			preferences := make([]ScriptPreference, 0)
-			for _, input := range c.extension.Preferences {
-				value, ok := msg.Values[input.Name]
-				if !ok {
-					continue
-				}
-				preference := ScriptPreference{
-					Name:      input.Name,
-					Value:     value,
-					Extension: c.extension.Name,
-				}
-				preferences = append(preferences, preference)
-			}
-
-			for _, input := range c.script.Preferences {
-				value, ok := msg.Values[input.Name]
-				if !ok {
-					continue
-				}
-				preference := ScriptPreference{
-					Name:      input.Name,
-					Value:     value,
-					Extension: c.extension.Name,
-					Script:    c.script.Name,
-				}
-				preferences = append(preferences, preference)
-			}
-
-			err := keyStore.SetPreference(preferences...)
+			err := c.preferences.Set(submittedPreferences(c.extension, c.script, msg.Values)...)
 			if err != nil {
 				return c, NewErrorCmd(err)
 			}
@@ -338,7 +677,11 @@ func (c *ScriptRunner) Update(msg tea.Msg) (Page, tea.Cmd) {
 			c.with[key] = value
 		}
 
-		return c, c.Run()
+		c.refresh = true
+		cmd := c.Run()
+		c.refresh = false
+
+		return c, cmd
 	}
 
 	var cmd tea.Cmd
@@ -0,0 +1,22 @@
+package tui
+
+import "testing"
+
+func TestOutputCacheKey(t *testing.T) {
+	key := outputCacheKey("jira list-issues", []string{"SUNBEAM_EXTENSION=jira", "HOST=work"}, []byte(`{"HOST":"work"}`))
+
+	if key != outputCacheKey("jira list-issues", []string{"SUNBEAM_EXTENSION=jira", "HOST=work"}, []byte(`{"HOST":"work"}`)) {
+		t.Error("expected the key to be stable")
+	}
+
+	for name, other := range map[string]string{
+		"command":     outputCacheKey("jira list-projects", []string{"SUNBEAM_EXTENSION=jira", "HOST=work"}, []byte(`{"HOST":"work"}`)),
+		"env":         outputCacheKey("jira list-issues", []string{"SUNBEAM_EXTENSION=jira", "HOST=personal"}, []byte(`{"HOST":"work"}`)),
+		"preferences": outputCacheKey("jira list-issues", []string{"SUNBEAM_EXTENSION=jira", "HOST=work"}, []byte(`{"HOST":"personal"}`)),
+		"boundaries":  outputCacheKey("jira list-issues", []string{"SUNBEAM_EXTENSION=jiraHOST=work"}, []byte(`{"HOST":"work"}`)),
+	} {
+		if other == key {
+			t.Errorf("expected a different key when the %s changes", name)
+		}
+	}
+}
@@ -1,21 +1,83 @@
 package tui
 
 import (
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
 	"github.com/charmbracelet/lipgloss"
 )
 
 type Styles struct {
-	Bold   lipgloss.Style
-	Faint  lipgloss.Style
-	Italic lipgloss.Style
+	Bold      lipgloss.Style
+	Faint     lipgloss.Style
+	Italic    lipgloss.Style
+	Accent    lipgloss.Style
+	Error     lipgloss.Style
+	Border    lipgloss.Style
+	Selection lipgloss.Style
+	Tag       lipgloss.Style
+
+	// Markdown renders the markdown content
+	Markdown ansi.StyleConfig
+	// Code is the chroma style of the code content, it is not highlighted if empty
+	Code string
 }
 
 var styles Styles
 
 func init() {
-	styles = Styles{
-		Bold:   lipgloss.NewStyle().Bold(true),
-		Faint:  lipgloss.NewStyle().Faint(true),
-		Italic: lipgloss.NewStyle().Italic(true),
+	styles = NewStyles(DarkTheme)
+}
+
+// NewStyles derives the styles used by the components from a theme.
+// Empty colors are not applied, the faint style falls back to the faint text attribute.
+func NewStyles(theme Theme) Styles {
+	withColor := func(style lipgloss.Style, color string) lipgloss.Style {
+		if color == "" {
+			return style
+		}
+		return style.Foreground(lipgloss.Color(color))
+	}
+
+	faint := lipgloss.NewStyle()
+	if theme.Faint == "" {
+		faint = faint.Faint(true)
+	}
+
+	return Styles{
+		Bold:      lipgloss.NewStyle().Bold(true),
+		Faint:     withColor(faint, theme.Faint),
+		Italic:    lipgloss.NewStyle().Italic(true),
+		Accent:    withColor(lipgloss.NewStyle(), theme.Accent),
+		Error:     withColor(lipgloss.NewStyle(), theme.Error),
+		Border:    withColor(lipgloss.NewStyle(), theme.Border),
+		Selection: withColor(lipgloss.NewStyle().Bold(true), theme.Selection),
+		Tag:       withColor(lipgloss.NewStyle(), theme.Tag),
+		Markdown:  markdownStyle(theme),
+		Code:      theme.Code,
 	}
 }
+
+// markdownStyle customizes the glamour style of the theme with its accent color and its code style.
+func markdownStyle(theme Theme) ansi.StyleConfig {
+	base, ok := glamour.DefaultStyles[theme.Markdown]
+	if !ok {
+		base = &glamour.NoTTYStyleConfig
+	}
+
+	// The nested pointers are shared with the glamour style, so they are replaced rather than modified
+	style := *base
+	if theme.Accent != "" {
+		accent := theme.Accent
+		style.Heading.Color = &accent
+		style.H1.Color = nil
+		style.H1.BackgroundColor = nil
+		style.Link.Color = &accent
+		style.LinkText.Color = &accent
+	}
+	if theme.Code != "" {
+		style.CodeBlock.Theme = theme.Code
+		style.CodeBlock.Chroma = nil
+	}
+
+	return style
+}
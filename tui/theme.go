@@ -0,0 +1,179 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Theme defines the colors used by the components.
+// Colors are ANSI color numbers (e.g. 13) or hex codes (e.g. #ff00ff).
+type Theme struct {
+	Accent    string `yaml:"accent"`
+	Faint     string `yaml:"faint"`
+	Border    string `yaml:"border"`
+	Error     string `yaml:"error"`
+	Selection string `yaml:"selection"`
+	// Tag is the text color of the tags, drawn over their own color
+	Tag string `yaml:"tag"`
+
+	// Markdown is the glamour style the markdown content is based on: dark, light, dracula, pink, ascii or notty
+	Markdown string `yaml:"markdown"`
+	// Code is the chroma style highlighting the code content, e.g. monokai
+	Code string `yaml:"code"`
+}
+
+var DarkTheme = Theme{
+	Accent:    "13",
+	Faint:     "8",
+	Border:    "8",
+	Error:     "9",
+	Selection: "13",
+	Tag:       "0",
+	Markdown:  "dark",
+	Code:      "monokai",
+}
+
+var LightTheme = Theme{
+	Accent:    "5",
+	Faint:     "8",
+	Border:    "7",
+	Error:     "1",
+	Selection: "5",
+	Tag:       "15",
+	Markdown:  "light",
+	Code:      "github",
+}
+
+// NoColorTheme only relies on text attributes, it is used when NO_COLOR is set to a non-empty value.
+var NoColorTheme = Theme{
+	Markdown: "notty",
+}
+
+// extend fills the colors missing from the theme with the ones of the base theme.
+func (t Theme) extend(base Theme) Theme {
+	if t.Accent == "" {
+		t.Accent = base.Accent
+	}
+	if t.Faint == "" {
+		t.Faint = base.Faint
+	}
+	if t.Border == "" {
+		t.Border = base.Border
+	}
+	if t.Error == "" {
+		t.Error = base.Error
+	}
+	if t.Selection == "" {
+		t.Selection = base.Selection
+	}
+	if t.Tag == "" {
+		t.Tag = base.Tag
+	}
+	if t.Markdown == "" {
+		t.Markdown = base.Markdown
+	}
+	if t.Code == "" {
+		t.Code = base.Code
+	}
+	return t
+}
+
+// LoadThemes reads the theme files (<name>.yml) of a directory.
+func LoadThemes(themeDir string) (map[string]Theme, error) {
+	entries, err := os.ReadDir(themeDir)
+	if os.IsNotExist(err) {
+		return map[string]Theme{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	themes := make(map[string]Theme)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		theme, err := LoadTheme(filepath.Join(themeDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		themes[strings.TrimSuffix(entry.Name(), ext)] = theme
+	}
+
+	return themes, nil
+}
+
+func LoadTheme(themePath string) (Theme, error) {
+	content, err := os.ReadFile(themePath)
+	if err != nil {
+		return Theme{}, err
+	}
+
+	var theme Theme
+	if err := yaml.Unmarshal(content, &theme); err != nil {
+		return Theme{}, fmt.Errorf("invalid theme %s: %w", themePath, err)
+	}
+
+	return theme, nil
+}
+
+// ResolveTheme picks the theme to use from the config.
+// The theme can be dark, light, auto (the default), the name of a theme or the path to a theme file.
+func ResolveTheme(config *Config) (Theme, error) {
+	if noColor() {
+		return NoColorTheme, nil
+	}
+
+	// The background is only queried when needed, since the terminal can take a while to answer
+	base := func() Theme {
+		if lipgloss.HasDarkBackground() {
+			return DarkTheme
+		}
+		return LightTheme
+	}
+
+	switch config.Theme {
+	case "", "auto":
+		return base(), nil
+	case "dark":
+		return DarkTheme, nil
+	case "light":
+		return LightTheme, nil
+	}
+
+	if theme, ok := config.Themes[config.Theme]; ok {
+		return theme.extend(base()), nil
+	}
+
+	if ext := filepath.Ext(config.Theme); ext == ".yml" || ext == ".yaml" {
+		theme, err := LoadTheme(config.Theme)
+		if err != nil {
+			return Theme{}, err
+		}
+		return theme.extend(base()), nil
+	}
+
+	return Theme{}, fmt.Errorf("unknown theme: %s", config.Theme)
+}
+
+// SetTheme replaces the styles used by all the components.
+// It must be called before the pages are created.
+func SetTheme(theme Theme) {
+	if noColor() {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	styles = NewStyles(theme)
+}
+
+// noColor follows the NO_COLOR convention, colors are only disabled by a non-empty value.
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
@@ -0,0 +1,21 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveThemeNoColor(t *testing.T) {
+	config := &Config{Theme: "dark"}
+
+	t.Setenv("NO_COLOR", "1")
+	if theme, err := ResolveTheme(config); err != nil || !reflect.DeepEqual(theme, NoColorTheme) {
+		t.Errorf("expected the no color theme, got %+v, %v", theme, err)
+	}
+
+	// An empty value does not disable the colors
+	t.Setenv("NO_COLOR", "")
+	if theme, err := ResolveTheme(config); err != nil || !reflect.DeepEqual(theme, DarkTheme) {
+		t.Errorf("expected the dark theme, got %+v, %v", theme, err)
+	}
+}
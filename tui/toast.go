@@ -0,0 +1,137 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/sunbeamlauncher/sunbeam/utils"
+)
+
+const (
+	ToastSuccess = "success"
+	ToastInfo    = "info"
+	ToastError   = "error"
+)
+
+// Toast is a transient message displayed on top of the footer.
+type Toast struct {
+	Level string
+	Text  string
+}
+
+// toastDuration is the delay after which a toast is dismissed.
+var toastDuration = 3 * time.Second
+
+func NewToastCmd(level string, text string) tea.Cmd {
+	return func() tea.Msg {
+		return ToastMsg{
+			Level: level,
+			Text:  text,
+		}
+	}
+}
+
+func NewErrorToastCmd(err error) tea.Cmd {
+	return NewToastCmd(ToastError, err.Error())
+}
+
+type ToastMsg struct {
+	Level string
+	Text  string
+}
+
+type dismissToastMsg struct {
+	id int
+}
+
+func newDismissToastCmd(id int) tea.Cmd {
+	return tea.Tick(toastDuration, func(_ time.Time) tea.Msg {
+		return dismissToastMsg{id: id}
+	})
+}
+
+func (t Toast) View(width int) string {
+	var icon string
+	var style lipgloss.Style
+	switch t.Level {
+	case ToastError:
+		icon, style = "✗", styles.Error
+	case ToastInfo:
+		icon, style = "•", lipgloss.NewStyle()
+	default:
+		icon, style = "✓", styles.Accent
+	}
+
+	// Toasts are single line, only the first line of the text is kept
+	text, _, _ := strings.Cut(strings.TrimSpace(t.Text), "\n")
+	text = fmt.Sprintf(" %s %s", icon, text)
+	text = lipgloss.NewStyle().MaxWidth(width).Render(text)
+
+	blanks := strings.Repeat(" ", utils.Max(0, width-lipgloss.Width(text)))
+	return style.Render(text) + blanks
+}
+
+// overlayToast draws the toast over the last line of the view, where the footer lives.
+func overlayToast(view string, toast Toast, width int) string {
+	lines := strings.Split(view, "\n")
+	lines[len(lines)-1] = toast.View(width)
+	return strings.Join(lines, "\n")
+}
+
+// toastPattern matches the lines of stderr used by scripts to send toasts, e.g. ::toast level=success::Entry deleted
+var toastPattern = regexp.MustCompile(`^::toast(?: level=(success|info|error))?::(.*)$`)
+
+// runCommand runs the command and extracts the toasts sent by the script on stderr.
+// The other stderr lines are kept in the exit error.
+func runCommand(command *exec.Cmd) (string, []ToastMsg, error) {
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	output, err := command.Output()
+
+	var toasts []ToastMsg
+	var lines []string
+	scanner := bufio.NewScanner(&stderr)
+	for scanner.Scan() {
+		matches := toastPattern.FindStringSubmatch(scanner.Text())
+		if matches == nil {
+			lines = append(lines, scanner.Text())
+			continue
+		}
+
+		level := matches[1]
+		if level == "" {
+			level = ToastInfo
+		}
+		toasts = append(toasts, ToastMsg{Level: level, Text: matches[2]})
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Stderr = []byte(strings.Join(lines, "\n"))
+	}
+
+	return string(output), toasts, err
+}
+
+// withToasts delivers the toasts after the message.
+func withToasts(msg tea.Msg, toasts []ToastMsg) tea.Msg {
+	if len(toasts) == 0 {
+		return msg
+	}
+
+	cmds := []tea.Cmd{func() tea.Msg { return msg }}
+	for _, toast := range toasts {
+		toast := toast
+		cmds = append(cmds, func() tea.Msg { return toast })
+	}
+
+	return tea.Sequence(cmds...)()
+}
@@ -0,0 +1,265 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsArchive reports whether the path or url points to an archive supported by ExtractArchive.
+func IsArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxArchiveSize caps the size of the extracted files, against zip and gzip bombs.
+var maxArchiveSize int64 = 512 << 20
+
+// ExtractArchive extracts a .tar.gz or a .zip archive in the target directory.
+// Entries escaping the target directory are rejected, as well as symlinks pointing outside of it and special files.
+func ExtractArchive(archive string, target string) error {
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+
+	// The target is resolved, so that the symlinks can be compared to it
+	realTarget, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return err
+	}
+	e := &extractor{target: filepath.Clean(target), realTarget: realTarget, remaining: maxArchiveSize}
+
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		err = e.extractZip(archive)
+	} else {
+		err = e.extractTarGz(archive)
+	}
+	if err != nil {
+		return err
+	}
+
+	return e.createSymlinks()
+}
+
+type symlink struct {
+	name     string
+	path     string
+	linkname string
+}
+
+type extractor struct {
+	target     string
+	realTarget string
+	// remaining is the number of bytes which can still be extracted
+	remaining int64
+	// symlinks are created once the files are extracted, so that no file is written through them
+	symlinks []symlink
+}
+
+func (e *extractor) extractTarGz(archive string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		entryPath, err := archiveEntryPath(e.target, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(entryPath, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := e.writeFile(entryPath, tr, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			e.symlinks = append(e.symlinks, symlink{name: header.Name, path: entryPath, linkname: header.Linkname})
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			return fmt.Errorf("unsupported archive entry: %s", header.Name)
+		}
+	}
+}
+
+func (e *extractor) extractZip(archive string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, file := range r.File {
+		entryPath, err := archiveEntryPath(e.target, file.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(entryPath, 0755); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := file.Open()
+			if err != nil {
+				return err
+			}
+
+			err = e.writeFile(entryPath, rc, mode)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			// The target of zip symlinks is stored as their content
+			rc, err := file.Open()
+			if err != nil {
+				return err
+			}
+
+			linkname, err := io.ReadAll(io.LimitReader(rc, 4096))
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			e.symlinks = append(e.symlinks, symlink{name: file.Name, path: entryPath, linkname: string(linkname)})
+		default:
+			return fmt.Errorf("unsupported archive entry: %s", file.Name)
+		}
+	}
+
+	return nil
+}
+
+// createSymlinks creates the symlinks of the archive, their targets must exist in the target directory.
+// A symlink can point to another one, so they are created until no more can be.
+func (e *extractor) createSymlinks() error {
+	pending := e.symlinks
+	for len(pending) > 0 {
+		var next []symlink
+		for _, link := range pending {
+			ok, err := e.createSymlink(link)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				next = append(next, link)
+			}
+		}
+
+		if len(next) == len(pending) {
+			return fmt.Errorf("symlink %s points to a missing file: %s", next[0].name, next[0].linkname)
+		}
+		pending = next
+	}
+
+	return nil
+}
+
+// createSymlink creates a symlink whose resolved target is inside of the target directory.
+// It returns false if the target does not exist yet.
+func (e *extractor) createSymlink(link symlink) (bool, error) {
+	if filepath.IsAbs(link.linkname) {
+		return false, fmt.Errorf("symlink %s points outside of the target directory: %s", link.name, link.linkname)
+	}
+
+	// The parent directory can itself be reached through a symlink
+	parent, err := filepath.EvalSymlinks(filepath.Dir(link.path))
+	if err != nil {
+		return false, err
+	}
+	if !isWithin(e.realTarget, parent) {
+		return false, fmt.Errorf("symlink %s is outside of the target directory", link.name)
+	}
+
+	// The link is not cleaned before being resolved, since a ".." following a symlink is relative to its target
+	resolved, err := filepath.EvalSymlinks(parent + string(os.PathSeparator) + link.linkname)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !isWithin(e.realTarget, resolved) {
+		return false, fmt.Errorf("symlink %s points outside of the target directory: %s", link.name, link.linkname)
+	}
+
+	if err := os.Symlink(link.linkname, link.path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// archiveEntryPath returns the path of an archive entry in the target directory, and fails if it is outside of it.
+func archiveEntryPath(target string, name string) (string, error) {
+	target = filepath.Clean(target)
+	entryPath := filepath.Join(target, name)
+	if filepath.IsAbs(name) || !isWithin(target, entryPath) {
+		return "", fmt.Errorf("archive entry %s is outside of the target directory", name)
+	}
+
+	return entryPath, nil
+}
+
+func isWithin(dir string, p string) bool {
+	return p == dir || strings.HasPrefix(p, dir+string(os.PathSeparator))
+}
+
+// writeFile writes an archive entry, the extraction fails once the archive exceeds maxArchiveSize.
+func (e *extractor) writeFile(entryPath string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(entryPath), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(entryPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+
+	// One more byte is read to detect archives exceeding the limit
+	n, err := io.Copy(f, io.LimitReader(r, e.remaining+1))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if n > e.remaining {
+		f.Close()
+		return fmt.Errorf("archive exceeds the maximum size of %d MiB", maxArchiveSize>>20)
+	}
+	e.remaining -= n
+
+	return f.Close()
+}
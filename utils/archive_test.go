@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func writeTarGz(t *testing.T, archive string, headers ...tar.Header) {
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, header := range headers {
+		header := header
+		isFile := header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA
+		if isFile && header.Size == 0 {
+			header.Size = int64(len("content"))
+		}
+		if err := tw.WriteHeader(&header); err != nil {
+			t.Fatal(err)
+		}
+		if isFile {
+			tw.Write(bytes.Repeat([]byte("content"), int(header.Size)/len("content")+1)[:header.Size])
+		}
+	}
+	tw.Close()
+	gz.Close()
+}
+
+func writeZip(t *testing.T, archive string, names ...string) {
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("content"))
+	}
+	zw.Close()
+}
+
+func TestExtractArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive := path.Join(dir, "extension.tar.gz")
+	writeTarGz(t, archive,
+		tar.Header{Name: "extension/", Typeflag: tar.TypeDir, Mode: 0755},
+		tar.Header{Name: "extension/sunbeam.yml", Typeflag: tar.TypeReg, Mode: 0644},
+		tar.Header{Name: "extension/scripts/run.sh", Typeflag: tar.TypeReg, Mode: 0755},
+		// Old archives use the legacy type of the regular files
+		tar.Header{Name: "extension/README.md", Typeflag: tar.TypeRegA, Mode: 0644},
+		// The symlinks are created once their target exists, even when it is another symlink
+		tar.Header{Name: "extension/run", Typeflag: tar.TypeSymlink, Linkname: "bin/run"},
+		tar.Header{Name: "extension/bin", Typeflag: tar.TypeSymlink, Linkname: "scripts"},
+		tar.Header{Name: "extension/bin/run", Typeflag: tar.TypeSymlink, Linkname: "run.sh"},
+	)
+
+	target := path.Join(dir, "target")
+	if err := ExtractArchive(archive, target); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(path.Join(target, "extension", "scripts", "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm()&0100 == 0 {
+		t.Errorf("the executable mode was not kept: %s", fi.Mode())
+	}
+
+	for _, name := range []string{"README.md", "run"} {
+		if content, err := os.ReadFile(path.Join(target, "extension", name)); err != nil || string(content) != "content" {
+			t.Errorf("%s: expected the content of the file, got %q, %v", name, content, err)
+		}
+	}
+}
+
+func TestExtractArchiveSize(t *testing.T) {
+	defer func(size int64) { maxArchiveSize = size }(maxArchiveSize)
+	maxArchiveSize = 100
+
+	dir := t.TempDir()
+	archive := path.Join(dir, "bomb.tar.gz")
+	writeTarGz(t, archive,
+		tar.Header{Name: "a", Typeflag: tar.TypeReg, Mode: 0644, Size: 60},
+		tar.Header{Name: "b", Typeflag: tar.TypeReg, Mode: 0644, Size: 60},
+	)
+
+	if err := ExtractArchive(archive, path.Join(dir, "target")); err == nil || !strings.Contains(err.Error(), "maximum size") {
+		t.Errorf("expected the archive to exceed the maximum size, got %v", err)
+	}
+
+	archive = path.Join(dir, "small.tar.gz")
+	writeTarGz(t, archive, tar.Header{Name: "a", Typeflag: tar.TypeReg, Mode: 0644, Size: 100})
+	if err := ExtractArchive(archive, path.Join(dir, "small")); err != nil {
+		t.Errorf("expected the archive to fit, got %v", err)
+	}
+}
+
+func TestExtractArchiveTraversal(t *testing.T) {
+	dir := t.TempDir()
+
+	tarCases := map[string][]tar.Header{
+		"parent":           {{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644}},
+		"nested":           {{Name: "extension/../../evil", Typeflag: tar.TypeReg, Mode: 0644}},
+		"absolute":         {{Name: "/tmp/evil", Typeflag: tar.TypeReg, Mode: 0644}},
+		"symlink":          {{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+		"relative symlink": {{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../evil"}},
+		"missing target":   {{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "missing"}},
+		// Each symlink is inside of the target, but the second one escapes through the first one
+		"chained symlinks": {
+			{Name: "escape", Typeflag: tar.TypeSymlink, Linkname: "self/.."},
+			{Name: "self", Typeflag: tar.TypeSymlink, Linkname: "."},
+		},
+	}
+	for name, headers := range tarCases {
+		archive := path.Join(dir, name+".tar.gz")
+		writeTarGz(t, archive, headers...)
+
+		if err := ExtractArchive(archive, path.Join(dir, "target", name)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	archive := path.Join(dir, "evil.zip")
+	writeZip(t, archive, "../evil")
+	if err := ExtractArchive(archive, path.Join(dir, "target")); err == nil {
+		t.Error("zip: expected an error")
+	}
+
+	if _, err := os.Stat(path.Join(dir, "evil")); !os.IsNotExist(err) {
+		t.Error("a file was written outside of the target directory")
+	}
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52"
+)
+
+const ClipboardAuto = "auto"
+
+type clipboardBackend struct {
+	name      string
+	available func() bool
+	copy      func(text string) error
+}
+
+// clipboardBackends are listed by order of preference, osc52 is always available so it is the fallback.
+var clipboardBackends = []clipboardBackend{
+	{
+		name:      "wl-copy",
+		available: func() bool { return hasEnv("WAYLAND_DISPLAY") && hasCommand("wl-copy") },
+		copy:      commandCopier("wl-copy"),
+	},
+	{
+		name:      "xclip",
+		available: func() bool { return hasEnv("DISPLAY") && hasCommand("xclip") },
+		copy:      commandCopier("xclip", "-selection", "clipboard"),
+	},
+	{
+		name:      "xsel",
+		available: func() bool { return hasEnv("DISPLAY") && hasCommand("xsel") },
+		copy:      commandCopier("xsel", "--clipboard", "--input"),
+	},
+	// Over SSH, pbcopy and tmux would copy to the clipboard of the host running sunbeam, not the one of the user
+	{
+		name:      "osc52",
+		available: func() bool { return hasEnv("SSH_TTY") || hasEnv("SSH_CONNECTION") },
+		copy:      osc52Copy,
+	},
+	{
+		name:      "pbcopy",
+		available: func() bool { return hasCommand("pbcopy") },
+		copy:      commandCopier("pbcopy"),
+	},
+	{
+		name:      "tmux",
+		available: func() bool { return hasEnv("TMUX") && hasCommand("tmux") },
+		// -w also forwards the buffer to the clipboard of the terminal
+		copy: commandCopier("tmux", "load-buffer", "-w", "-"),
+	},
+	{
+		name:      "windows",
+		available: func() bool { return runtime.GOOS == "windows" },
+		copy:      clipboard.WriteAll,
+	},
+	{
+		name:      "osc52",
+		available: func() bool { return true },
+		copy:      osc52Copy,
+	},
+}
+
+func osc52Copy(text string) error {
+	osc52.NewOutput(os.Stderr, os.Environ()).Copy(text)
+	return nil
+}
+
+// CopyText copies the text to the clipboard.
+// The backend is detected from the environment, unless one is given explicitly.
+func CopyText(text string, backend string) error {
+	b, err := selectClipboardBackend(backend)
+	if err != nil {
+		return err
+	}
+
+	if err := b.copy(text); err != nil {
+		return fmt.Errorf("%s: %w", b.name, err)
+	}
+	return nil
+}
+
+// selectClipboardBackend returns the first available backend, or the one with the given name.
+func selectClipboardBackend(backend string) (clipboardBackend, error) {
+	if backend == "" || backend == ClipboardAuto {
+		for _, b := range clipboardBackends {
+			if b.available() {
+				return b, nil
+			}
+		}
+	}
+
+	for _, b := range clipboardBackends {
+		if b.name == backend {
+			return b, nil
+		}
+	}
+
+	return clipboardBackend{}, fmt.Errorf("unknown clipboard backend: %s", backend)
+}
+
+func commandCopier(name string, args ...string) func(string) error {
+	return func(text string) error {
+		// Some copiers, such as wl-copy, fork a child which keeps serving the clipboard.
+		// It inherits stderr, so a pipe would only be closed once the clipboard is overwritten.
+		stderr, err := os.CreateTemp("", "sunbeam-clipboard-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(stderr.Name())
+		defer stderr.Close()
+
+		cmd := exec.Command(name, args...)
+		cmd.Stdin = strings.NewReader(text)
+		cmd.Stderr = stderr
+
+		if err := cmd.Run(); err != nil {
+			if message, _ := os.ReadFile(stderr.Name()); len(bytes.TrimSpace(message)) > 0 {
+				return fmt.Errorf("%w: %s", err, bytes.TrimSpace(message))
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+func hasEnv(key string) bool {
+	return os.Getenv(key) != ""
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
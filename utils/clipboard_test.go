@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCommands creates executables doing nothing in a directory, and makes it the only one of the PATH.
+func fakeCommands(t *testing.T, names ...string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(path.Join(dir, name), []byte("#!/bin/sh\ncat > /dev/null\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestSelectClipboardBackend(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the windows backend is always available")
+	}
+
+	cases := map[string]struct {
+		env      map[string]string
+		commands []string
+		backend  string
+		want     string
+	}{
+		"wayland": {
+			env:      map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"},
+			commands: []string{"wl-copy", "xclip", "pbcopy"},
+			want:     "wl-copy",
+		},
+		"wayland without wl-copy": {
+			env:      map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"},
+			commands: []string{"xsel"},
+			want:     "xsel",
+		},
+		"xclip before xsel": {
+			env:      map[string]string{"DISPLAY": ":0"},
+			commands: []string{"xsel", "xclip"},
+			want:     "xclip",
+		},
+		"x11 commands without a display": {
+			commands: []string{"wl-copy", "xclip", "pbcopy"},
+			want:     "pbcopy",
+		},
+		"tmux": {
+			env:      map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0"},
+			commands: []string{"tmux"},
+			want:     "tmux",
+		},
+		"tmux over ssh": {
+			env:      map[string]string{"TMUX": "/tmp/tmux-1000/default,1,0", "SSH_CONNECTION": "10.0.0.1 51234 10.0.0.2 22"},
+			commands: []string{"tmux", "pbcopy"},
+			want:     "osc52",
+		},
+		"x11 forwarding over ssh": {
+			env:      map[string]string{"DISPLAY": "localhost:10.0", "SSH_TTY": "/dev/pts/1"},
+			commands: []string{"xclip"},
+			want:     "xclip",
+		},
+		"fallback": {
+			env:  map[string]string{"DISPLAY": ":0", "TMUX": "/tmp/tmux-1000/default,1,0"},
+			want: "osc52",
+		},
+		"explicit": {
+			env:      map[string]string{"DISPLAY": ":0"},
+			commands: []string{"xclip"},
+			backend:  "osc52",
+			want:     "osc52",
+		},
+		"auto": {
+			env:      map[string]string{"DISPLAY": ":0"},
+			commands: []string{"xclip"},
+			backend:  ClipboardAuto,
+			want:     "xclip",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, env := range []string{"WAYLAND_DISPLAY", "DISPLAY", "TMUX", "SSH_TTY", "SSH_CONNECTION"} {
+				t.Setenv(env, tc.env[env])
+			}
+			fakeCommands(t, tc.commands...)
+
+			backend, err := selectClipboardBackend(tc.backend)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if backend.name != tc.want {
+				t.Errorf("got backend %s, want %s", backend.name, tc.want)
+			}
+		})
+	}
+
+	if _, err := selectClipboardBackend("clippy"); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
+
+func TestCommandCopier(t *testing.T) {
+	dir := t.TempDir()
+	output := path.Join(dir, "clipboard")
+
+	// Like wl-copy, the copier leaves a child running in the background
+	script := path.Join(dir, "copy")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat > \"$1\"\nsleep 5 &\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := commandCopier(script, output)("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected the copy not to wait for the background child, took %s", elapsed)
+	}
+
+	if content, err := os.ReadFile(output); err != nil || string(content) != "hello" {
+		t.Errorf("expected the text to be copied, got %q, %v", content, err)
+	}
+
+	failing := path.Join(dir, "fail")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\necho 'no display' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := commandCopier(failing)("hello"); err == nil || !strings.Contains(err.Error(), "no display") {
+		t.Errorf("expected the stderr of the command in the error, got %v", err)
+	}
+}
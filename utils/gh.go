@@ -1,12 +1,23 @@
 package utils
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 const (
@@ -21,10 +32,29 @@ const (
 	userAgent       = "User-Agent"
 )
 
+const (
+	maxRetries = 3
+	// maxRateLimitWait is the longest wait for a rate limit reset, the request fails beyond it
+	maxRateLimitWait = time.Minute
+)
+
+// DefaultGHHost returns the host of the GitHub instance, GH_HOST takes precedence over the configured host.
+func DefaultGHHost(configured string) string {
+	if host := os.Getenv("GH_HOST"); host != "" {
+		return host
+	}
+	if configured != "" {
+		return configured
+	}
+	return github
+}
+
 func NewGHClient(host string) RestClient {
 	return RestClient{
 		client: *http.DefaultClient,
 		host:   host,
+		token:  GHToken(host),
+		sleep:  time.Sleep,
 	}
 }
 
@@ -53,9 +83,79 @@ func normalizeHostname(h string) string {
 	return strings.ToLower(strings.TrimPrefix(h, "www."))
 }
 
+// GHToken returns the token used for a host, from the environment or from the gh cli.
+func GHToken(host string) string {
+	envs := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if isEnterprise(normalizeHostname(host)) {
+		envs = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+
+	for _, env := range envs {
+		if token := os.Getenv(env); token != "" {
+			return token
+		}
+	}
+
+	if token := ghConfigToken(host); token != "" {
+		return token
+	}
+
+	// Recent versions of gh store the token in the system keyring
+	if _, err := exec.LookPath("gh"); err == nil {
+		output, err := exec.Command("gh", "auth", "token", "--hostname", host).Output()
+		if err == nil {
+			return strings.TrimSpace(string(output))
+		}
+	}
+
+	return ""
+}
+
+// ghConfigToken reads the token of a host from the hosts.yml file of the gh cli.
+func ghConfigToken(host string) string {
+	configDir := os.Getenv("GH_CONFIG_DIR")
+	if configDir == "" {
+		configHome, err := ConfigHome()
+		if err != nil {
+			return ""
+		}
+		configDir = path.Join(configHome, "gh")
+	}
+
+	content, err := os.ReadFile(path.Join(configDir, "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+
+	var hosts map[string]struct {
+		OauthToken string `yaml:"oauth_token"`
+	}
+	if err := yaml.Unmarshal(content, &hosts); err != nil {
+		return ""
+	}
+
+	return hosts[normalizeHostname(host)].OauthToken
+}
+
 type RestClient struct {
 	client http.Client
 	host   string
+	token  string
+	sleep  func(time.Duration)
+}
+
+// HTTPError is returned for the responses with an unsuccessful status.
+type HTTPError struct {
+	StatusCode int
+	Message    string
+	Url        string
+}
+
+func (e HTTPError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("HTTP %d: %s (%s)", e.StatusCode, e.Message, e.Url)
+	}
+	return fmt.Sprintf("HTTP %d (%s)", e.StatusCode, e.Url)
 }
 
 func (c RestClient) Get(path string, resp interface{}) error {
@@ -67,39 +167,175 @@ func (c RestClient) Do(method string, path string, body io.Reader, response inte
 }
 
 func (c RestClient) DoWithContext(ctx context.Context, method string, path string, body io.Reader, response interface{}) error {
-	url := restURL(c.host, path)
-	req, err := http.NewRequestWithContext(ctx, method, url, body)
-	if err != nil {
+	_, err := c.do(ctx, method, restURL(c.host, path), body, func(resp *http.Response) error {
+		return decodeResponse(resp, response)
+	})
+	return err
+}
+
+// Download writes the content of a url to w, such as the raw url of a gist file.
+// The token is only sent if the url belongs to the API of the host.
+func (c RestClient) Download(url string, w io.Writer) error {
+	_, err := c.do(context.Background(), http.MethodGet, url, nil, func(resp *http.Response) error {
+		_, err := io.Copy(w, resp.Body)
 		return err
+	})
+	return err
+}
+
+// GetAll fetches all the pages of a list endpoint, by following the next links of the responses.
+func GetAll[T any](c RestClient, path string) ([]T, error) {
+	items := make([]T, 0)
+	url := restURL(c.host, path)
+	for url != "" {
+		var page []T
+		next, err := c.do(context.Background(), http.MethodGet, url, nil, func(resp *http.Response) error {
+			return decodeResponse(resp, &page)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, page...)
+		url = next
 	}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return err
+	return items, nil
+}
+
+// do sends the request, and retries it when the rate limit is exceeded or the server fails.
+// Successful responses are passed to handle, the url of the next page is returned if the response is paginated.
+func (c RestClient) do(ctx context.Context, method string, url string, body io.Reader, handle func(*http.Response) error) (string, error) {
+	// The body is buffered, so that it can be sent again
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = io.ReadAll(body); err != nil {
+			return "", err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+		if err != nil {
+			return "", err
+		}
+
+		req.Header.Set(accept, "application/vnd.github+json")
+		req.Header.Set(userAgent, "sunbeam")
+		if payload != nil {
+			req.Header.Set(contentType, jsonContentType)
+		}
+		// The urls can come from the responses, the token must not leak to other hosts
+		if c.token != "" && c.isAPI(req.URL) {
+			req.Header.Set(authorization, fmt.Sprintf("token %s", c.token))
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return "", err
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			return nextPage(resp.Header.Get("Link")), handle(resp)
+		}
+
+		httpErr := readHTTPError(resp)
+		wait, retry := retryDelay(resp, attempt)
+		if !retry || attempt >= maxRetries {
+			return "", httpErr
+		}
+		if wait > maxRateLimitWait {
+			return "", fmt.Errorf("%w, retry in %s", httpErr, wait.Round(time.Second))
+		}
+
+		c.sleep(wait)
 	}
+}
 
-	success := resp.StatusCode >= 200 && resp.StatusCode < 300
-	if !success {
-		defer resp.Body.Close()
-		return fmt.Errorf("error: %s", resp.Status)
+// isAPI reports whether the url belongs to the API of the host, using the same scheme.
+func (c RestClient) isAPI(u *url.URL) bool {
+	prefix, err := url.Parse(restPrefix(c.host))
+	if err != nil {
+		return false
 	}
 
-	if resp.StatusCode == http.StatusNoContent {
+	return u.Scheme == prefix.Scheme && strings.EqualFold(u.Host, prefix.Host)
+}
+
+func decodeResponse(resp *http.Response, response interface{}) error {
+	if resp.StatusCode == http.StatusNoContent || response == nil {
 		return nil
 	}
-	defer resp.Body.Close()
 
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
 
-	err = json.Unmarshal(b, &response)
-	if err != nil {
-		return err
+	return json.Unmarshal(b, &response)
+}
+
+func readHTTPError(resp *http.Response) HTTPError {
+	defer resp.Body.Close()
+
+	httpErr := HTTPError{
+		StatusCode: resp.StatusCode,
+		Url:        resp.Request.URL.String(),
+	}
+
+	var payload struct {
+		Message string `json:"message"`
 	}
+	if b, err := io.ReadAll(resp.Body); err == nil && json.Unmarshal(b, &payload) == nil {
+		httpErr.Message = payload.Message
+	}
+
+	return httpErr
+}
+
+// retryDelay returns how long to wait before retrying a failed request.
+// Rate limited requests wait for the reset of the limit, server errors use an exponential backoff.
+func retryDelay(resp *http.Response, attempt int) (time.Duration, bool) {
+	backoff := time.Duration(1<<attempt) * time.Second
+
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return time.Duration(retryAfter) * time.Second, true
+		}
+
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+			if err != nil {
+				return backoff, true
+			}
+			return time.Until(time.Unix(reset, 0)) + time.Second, true
+		}
+
+		// Secondary rate limits are not always documented by headers
+		return backoff, resp.StatusCode == http.StatusTooManyRequests
+	}
+
+	return backoff, resp.StatusCode >= 500
+}
+
+var linkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="([^"]+)"`)
+
+// nextPage extracts the url of the next page from a Link header.
+func nextPage(link string) string {
+	for _, match := range linkPattern.FindAllStringSubmatch(link, -1) {
+		if match[2] == "next" {
+			return match[1]
+		}
+	}
+	return ""
+}
 
-	return nil
+// IsNotFound reports whether the error is a 404 response.
+func IsNotFound(err error) bool {
+	var httpErr HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
 }
 
 func isGarage(host string) bool {
@@ -0,0 +1,306 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client of the test server, which is handled as a GitHub Enterprise host.
+func newTestClient(server *httptest.Server, token string) (RestClient, *[]time.Duration) {
+	var sleeps []time.Duration
+	return RestClient{
+		client: *server.Client(),
+		host:   server.Listener.Addr().String(),
+		token:  token,
+		sleep: func(d time.Duration) {
+			sleeps = append(sleeps, d)
+		},
+	}, &sleeps
+}
+
+func TestRestPrefix(t *testing.T) {
+	cases := map[string]string{
+		"github.com":        "https://api.github.com/",
+		"www.github.com":    "https://api.github.com/",
+		"github.localhost":  "http://api.github.localhost/",
+		"garage.github.com": "https://garage.github.com/api/v3/",
+		"ghe.example.com":   "https://ghe.example.com/api/v3/",
+		"GHE.example.com":   "https://ghe.example.com/api/v3/",
+	}
+
+	for host, want := range cases {
+		if got := restPrefix(host); got != want {
+			t.Errorf("restPrefix(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/user" {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprintf(w, `{"authorization": %q}`, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	for token, want := range map[string]string{"secret": "token secret", "": ""} {
+		client, _ := newTestClient(server, token)
+
+		var res struct {
+			Authorization string
+		}
+		if err := client.Get("user", &res); err != nil {
+			t.Fatal(err)
+		}
+
+		if res.Authorization != want {
+			t.Errorf("got authorization %q, want %q", res.Authorization, want)
+		}
+	}
+}
+
+func TestGetAll(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v3/repos?page=%d>; rel="next", <%s/api/v3/repos?page=3>; rel="last"`, server.URL, page+1, server.URL))
+		}
+		fmt.Fprintf(w, `[{"id": %d}, {"id": %d}]`, 2*page-1, 2*page)
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server, "")
+	repos, err := GetAll[struct{ Id int }](client, "repos")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repos) != 6 {
+		t.Fatalf("got %d items, want 6", len(repos))
+	}
+	for i, repo := range repos {
+		if repo.Id != i+1 {
+			t.Errorf("item %d has id %d", i, repo.Id)
+		}
+	}
+}
+
+func TestTokenScope(t *testing.T) {
+	// The other server stands for a host which is not the API of the client
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/raw" {
+			fmt.Fprintf(w, "raw authorization: %q", r.Header.Get("Authorization"))
+			return
+		}
+		fmt.Fprintf(w, `[{"authorization": %q}]`, r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gist/raw" {
+			fmt.Fprintf(w, "raw authorization: %q", r.Header.Get("Authorization"))
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos?page=2>; rel="next"`, other.URL))
+		fmt.Fprintf(w, `[{"authorization": %q}]`, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	client, _ := newTestClient(server, "secret")
+	pages, err := GetAll[struct{ Authorization string }](client, "repos")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pages) != 2 || pages[0].Authorization != "token secret" || pages[1].Authorization != "" {
+		t.Errorf("expected the token to only be sent to the API host, got %+v", pages)
+	}
+
+	for rawURL, want := range map[string]string{
+		server.URL + "/gist/raw": `raw authorization: "token secret"`,
+		other.URL + "/raw":       `raw authorization: ""`,
+	} {
+		var content strings.Builder
+		if err := client.Download(rawURL, &content); err != nil {
+			t.Fatal(err)
+		}
+		if content.String() != want {
+			t.Errorf("Download(%s) = %s, want %s", rawURL, content.String(), want)
+		}
+	}
+
+	// The scheme must match as well
+	if client.isAPI(&url.URL{Scheme: "http", Host: client.host}) {
+		t.Error("expected a plain http url not to be authorized")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Run("waits for the reset", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(10*time.Second).Unix(), 10))
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+				return
+			}
+			fmt.Fprint(w, `{}`)
+		}))
+		defer server.Close()
+
+		client, sleeps := newTestClient(server, "")
+		if err := client.Get("user", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(*sleeps) != 1 || (*sleeps)[0] < 5*time.Second || (*sleeps)[0] > 15*time.Second {
+			t.Errorf("unexpected waits: %v", *sleeps)
+		}
+	})
+
+	t.Run("honors retry-after", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, `{}`)
+		}))
+		defer server.Close()
+
+		client, sleeps := newTestClient(server, "")
+		if err := client.Get("user", nil); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(*sleeps) != 1 || (*sleeps)[0] != 7*time.Second {
+			t.Errorf("unexpected waits: %v", *sleeps)
+		}
+	})
+
+	t.Run("fails when the reset is too far", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		client, sleeps := newTestClient(server, "")
+		if err := client.Get("user", nil); err == nil {
+			t.Error("expected an error")
+		}
+		if len(*sleeps) != 0 {
+			t.Errorf("unexpected waits: %v", *sleeps)
+		}
+	})
+
+	t.Run("does not retry forbidden requests", func(t *testing.T) {
+		attempts := 0
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "Resource not accessible by integration"}`)
+		}))
+		defer server.Close()
+
+		client, _ := newTestClient(server, "")
+		err := client.Get("user", nil)
+		if err == nil || attempts != 1 {
+			t.Errorf("got error %v after %d attempts", err, attempts)
+		}
+	})
+}
+
+func TestServerErrorBackoff(t *testing.T) {
+	attempts := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client, sleeps := newTestClient(server, "")
+	err := client.Get("user", nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if attempts != maxRetries+1 {
+		t.Errorf("got %d attempts, want %d", attempts, maxRetries+1)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if fmt.Sprint(*sleeps) != fmt.Sprint(want) {
+		t.Errorf("got waits %v, want %v", *sleeps, want)
+	}
+}
+
+func TestGHToken(t *testing.T) {
+	configDir := t.TempDir()
+	hosts := "github.com:\n  oauth_token: from-config\nghe.example.com:\n  oauth_token: from-enterprise-config\n"
+	if err := os.WriteFile(path.Join(configDir, "hosts.yml"), []byte(hosts), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("GH_CONFIG_DIR", configDir)
+	// Prevents the gh cli from being used
+	t.Setenv("PATH", "")
+	for _, env := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(env, "")
+	}
+
+	if got := GHToken("github.com"); got != "from-config" {
+		t.Errorf("got %q from the gh config", got)
+	}
+	if got := GHToken("ghe.example.com"); got != "from-enterprise-config" {
+		t.Errorf("got %q from the gh config", got)
+	}
+
+	t.Setenv("GITHUB_TOKEN", "from-github-token")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "from-enterprise-env")
+	if got := GHToken("github.com"); got != "from-github-token" {
+		t.Errorf("got %q from GITHUB_TOKEN", got)
+	}
+	if got := GHToken("ghe.example.com"); got != "from-enterprise-env" {
+		t.Errorf("got %q from GH_ENTERPRISE_TOKEN", got)
+	}
+
+	t.Setenv("GH_TOKEN", "from-gh-token")
+	if got := GHToken("github.com"); got != "from-gh-token" {
+		t.Errorf("got %q, GH_TOKEN should take precedence", got)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	link := `<https://api.github.com/repos?page=2>; rel="next", <https://api.github.com/repos?page=5>; rel="last"`
+	if got := nextPage(link); got != "https://api.github.com/repos?page=2" {
+		t.Errorf("got %q", got)
+	}
+
+	if got := nextPage(`<https://api.github.com/repos?page=1>; rel="prev"`); got != "" {
+		t.Errorf("got %q for the last page", got)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
@@ -32,6 +33,28 @@ func (gc *GitClient) Pull() error {
 	return cmd.Run()
 }
 
+// Checkout fetches the commits of origin, and checks out the given commit in a detached HEAD.
+func (gc *GitClient) Checkout(commit string) error {
+	if strings.HasPrefix(commit, "-") {
+		return fmt.Errorf("invalid commit: %s", commit)
+	}
+
+	for _, args := range [][]string{
+		{"fetch", "--tags", "origin"},
+		{"-c", "advice.detachedHead=false", "checkout", "--detach", commit},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = gc.repo
+		cmd.Stderr = os.Stderr
+		cmd.Stdout = os.Stdout
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (gc *GitClient) Config(name string) (string, error) {
 	cmd := exec.Command("git", "config", name)
 	cmd.Dir = gc.repo
@@ -61,6 +84,7 @@ func (gc *GitClient) GetCurrentVersion() string {
 
 func (gc *GitClient) GetLatestVersion() (string, error) {
 	cmd := exec.Command("git", "ls-remote", "origin", "HEAD")
+	cmd.Dir = gc.repo
 	output, err := cmd.Output()
 	if err != nil {
 		return "", err
@@ -42,3 +42,134 @@ func ResolvePath(filepath string) (string, error) {
 func IsRoot(filepath string) bool {
 	return path.Dir(filepath) == filepath
 }
+
+// Dirs are the directories used by sunbeam.
+type Dirs struct {
+	// Config contains the config file, the themes and the preferences
+	Config string
+	// Data contains the installed extensions and their data
+	Data string
+	// Cache contains the cache of the extensions
+	Cache string
+	// State contains the history and the logs
+	State string
+}
+
+// ResolveDirs resolves the directories of sunbeam, following the XDG base directory specification.
+// SUNBEAM_HOME moves all of them to a single directory, and the config directory can be overridden on its own.
+func ResolveDirs(configDir string) (Dirs, error) {
+	var dirs Dirs
+	if home := os.Getenv("SUNBEAM_HOME"); home != "" {
+		home, err := ResolvePath(home)
+		if err != nil {
+			return Dirs{}, err
+		}
+
+		dirs = Dirs{
+			Config: home,
+			Data:   path.Join(home, "data"),
+			Cache:  path.Join(home, "cache"),
+			State:  path.Join(home, "state"),
+		}
+	} else {
+		for _, dir := range []struct {
+			target *string
+			home   func() (string, error)
+		}{
+			{&dirs.Config, ConfigHome},
+			{&dirs.Data, DataHome},
+			{&dirs.Cache, CacheHome},
+			{&dirs.State, StateHome},
+		} {
+			home, err := dir.home()
+			if err != nil {
+				return Dirs{}, err
+			}
+			*dir.target = path.Join(home, "sunbeam")
+		}
+	}
+
+	if configDir != "" {
+		configDir, err := ResolvePath(configDir)
+		if err != nil {
+			return Dirs{}, err
+		}
+		dirs.Config = configDir
+	}
+
+	return dirs, nil
+}
+
+func (d Dirs) Extensions() string {
+	return path.Join(d.Data, "extensions")
+}
+
+func (d Dirs) Preferences() string {
+	return path.Join(d.Config, "preferences.json")
+}
+
+// ProfilePreferences returns the path of the preferences of a profile.
+func (d Dirs) ProfilePreferences(profile string) string {
+	return path.Join(d.Config, "profiles", profile, "preferences.json")
+}
+
+// TrustedConfigs returns the file listing the trusted directory configs.
+func (d Dirs) TrustedConfigs() string {
+	return path.Join(d.State, "trusted.json")
+}
+
+// RegistryCache returns the directory caching the registry indexes.
+// The extension names cannot contain dots, so it never collides with the cache of an extension.
+func (d Dirs) RegistryCache() string {
+	return path.Join(d.Cache, ".sunbeam", "registry")
+}
+
+func (d Dirs) Themes() string {
+	return path.Join(d.Config, "themes")
+}
+
+func (d Dirs) History() string {
+	return path.Join(d.State, "history.json")
+}
+
+// ManifestCache returns the file caching the parsed manifests of the extensions.
+func (d Dirs) ManifestCache() string {
+	return path.Join(d.State, "manifests.gob")
+}
+
+func (d Dirs) Log() string {
+	return path.Join(d.State, "sunbeam.log")
+}
+
+// ConfigHome returns the base directory of the user config files, as defined by the XDG base directory specification.
+func ConfigHome() (string, error) {
+	return xdgHome("XDG_CONFIG_HOME", ".config")
+}
+
+// DataHome returns the base directory of the user data files, as defined by the XDG base directory specification.
+func DataHome() (string, error) {
+	return xdgHome("XDG_DATA_HOME", ".local/share")
+}
+
+// CacheHome returns the base directory of the user cache files, as defined by the XDG base directory specification.
+func CacheHome() (string, error) {
+	return xdgHome("XDG_CACHE_HOME", ".cache")
+}
+
+// StateHome returns the base directory of the user state files, as defined by the XDG base directory specification.
+func StateHome() (string, error) {
+	return xdgHome("XDG_STATE_HOME", ".local/state")
+}
+
+func xdgHome(env string, fallback string) (string, error) {
+	// Relative paths are invalid according to the specification
+	if dir := os.Getenv(env); path.IsAbs(dir) {
+		return dir, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(homeDir, fallback), nil
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestResolveDirs(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		env       map[string]string
+		configDir string
+		want      Dirs
+	}{
+		"defaults": {
+			want: Dirs{
+				Config: path.Join(home, ".config", "sunbeam"),
+				Data:   path.Join(home, ".local", "share", "sunbeam"),
+				Cache:  path.Join(home, ".cache", "sunbeam"),
+				State:  path.Join(home, ".local", "state", "sunbeam"),
+			},
+		},
+		"xdg": {
+			env: map[string]string{
+				"XDG_CONFIG_HOME": "/xdg/config",
+				"XDG_DATA_HOME":   "/xdg/data",
+				"XDG_CACHE_HOME":  "/xdg/cache",
+				"XDG_STATE_HOME":  "/xdg/state",
+			},
+			want: Dirs{Config: "/xdg/config/sunbeam", Data: "/xdg/data/sunbeam", Cache: "/xdg/cache/sunbeam", State: "/xdg/state/sunbeam"},
+		},
+		"relative xdg": {
+			env: map[string]string{"XDG_CONFIG_HOME": "config"},
+			want: Dirs{
+				Config: path.Join(home, ".config", "sunbeam"),
+				Data:   path.Join(home, ".local", "share", "sunbeam"),
+				Cache:  path.Join(home, ".cache", "sunbeam"),
+				State:  path.Join(home, ".local", "state", "sunbeam"),
+			},
+		},
+		"sunbeam home": {
+			env: map[string]string{
+				"SUNBEAM_HOME":  "/sunbeam",
+				"XDG_DATA_HOME": "/xdg/data",
+			},
+			want: Dirs{Config: "/sunbeam", Data: "/sunbeam/data", Cache: "/sunbeam/cache", State: "/sunbeam/state"},
+		},
+		"sunbeam home in the home directory": {
+			env:  map[string]string{"SUNBEAM_HOME": "~/sunbeam"},
+			want: Dirs{Config: path.Join(home, "sunbeam"), Data: path.Join(home, "sunbeam", "data"), Cache: path.Join(home, "sunbeam", "cache"), State: path.Join(home, "sunbeam", "state")},
+		},
+		"config dir": {
+			env:       map[string]string{"SUNBEAM_HOME": "/sunbeam"},
+			configDir: "dotfiles/sunbeam",
+			want:      Dirs{Config: path.Join(cwd, "dotfiles", "sunbeam"), Data: "/sunbeam/data", Cache: "/sunbeam/cache", State: "/sunbeam/state"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, env := range []string{"SUNBEAM_HOME", "XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME"} {
+				t.Setenv(env, tc.env[env])
+			}
+
+			dirs, err := ResolveDirs(tc.configDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dirs != tc.want {
+				t.Errorf("got %+v, want %+v", dirs, tc.want)
+			}
+		})
+	}
+}
+
+func TestRegistryCache(t *testing.T) {
+	dirs := Dirs{Cache: "/cache"}
+	// The extension caches are named after the extensions, which cannot contain dots
+	if got := dirs.RegistryCache(); got != "/cache/.sunbeam/registry" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+func ParseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// RelativeTime formats t relatively to now, e.g. "3 days ago" or "in 2 hours".
+func RelativeTime(t time.Time, now time.Time) string {
+	delta := now.Sub(t)
+	format := "%s ago"
+	if delta < 0 {
+		delta = -delta
+		format = "in %s"
+	}
+
+	if delta < time.Minute {
+		return "just now"
+	}
+
+	units := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"week", 7 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	for _, unit := range units {
+		count := int(delta / unit.duration)
+		if count == 0 {
+			continue
+		}
+
+		if count == 1 {
+			return fmt.Sprintf(format, fmt.Sprintf("1 %s", unit.name))
+		}
+		return fmt.Sprintf(format, fmt.Sprintf("%d %ss", count, unit.name))
+	}
+
+	return "just now"
+}
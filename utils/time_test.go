@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	cases := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2023-01-15T10:30:00Z", want: time.Date(2023, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{value: "2023-01-15T10:30:00+02:00", want: time.Date(2023, 1, 15, 8, 30, 0, 0, time.UTC)},
+		// Dates without a time are in the local timezone
+		{value: "2023-01-15", want: time.Date(2023, 1, 15, 0, 0, 0, 0, time.Local)},
+		{value: "15/01/2023", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseDate(tc.value)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("ParseDate(%q): expected an error, got %s", tc.value, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseDate(%q): unexpected error: %s", tc.value, err)
+		} else if !got.Equal(tc.want) {
+			t.Errorf("ParseDate(%q) = %s, want %s", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2023, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		delta time.Duration
+		want  string
+	}{
+		{0, "just now"},
+		{-30 * time.Second, "just now"},
+		{30 * time.Second, "just now"},
+		{-time.Minute, "1 minute ago"},
+		{-59 * time.Minute, "59 minutes ago"},
+		{-90 * time.Minute, "1 hour ago"},
+		{-23 * time.Hour, "23 hours ago"},
+		{-24 * time.Hour, "1 day ago"},
+		{-6 * 24 * time.Hour, "6 days ago"},
+		{-14 * 24 * time.Hour, "2 weeks ago"},
+		{-45 * 24 * time.Hour, "1 month ago"},
+		{-400 * 24 * time.Hour, "1 year ago"},
+		{-3 * 365 * 24 * time.Hour, "3 years ago"},
+		{2 * time.Hour, "in 2 hours"},
+		{24 * time.Hour, "in 1 day"},
+	}
+
+	for _, tc := range cases {
+		if got := RelativeTime(now.Add(tc.delta), now); got != tc.want {
+			t.Errorf("RelativeTime(now%+s) = %q, want %q", tc.delta, got, tc.want)
+		}
+	}
+}